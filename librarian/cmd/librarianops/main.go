@@ -100,10 +100,8 @@ func automateReleaseCommand(ctx context.Context, cmd *cli.Command) error {
 		fmt.Printf("Running automated release workflow (project: %s)...\n", project)
 	}
 
-	fmt.Println("\nStep 1: Releasing all prepared artifacts")
-	fmt.Println("  librarian release --all")
-	fmt.Println("\nStep 2: Creating GitHub releases")
-	fmt.Println("  gh release create --with-token=$(fetch token) --notes-from-tag")
+	fmt.Println("\nStep 1: Tagging and publishing all prepared artifacts")
+	fmt.Println("  GITHUB_TOKEN=$(fetch token) librarian release --all")
 
 	if !dryRun {
 		fmt.Println("\n⚠️  TODO: Implement actual automation logic")
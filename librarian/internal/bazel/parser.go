@@ -26,7 +26,13 @@ func ParseBuildFile(buildPath string, language string) (*state.API, error) {
 		return nil, fmt.Errorf("failed to parse BUILD.bazel: %w", err)
 	}
 
-	// Find the language-specific GAPIC rule
+	// Find the language-specific GAPIC rule, by kind if the language has a
+	// known rule and otherwise by the conventional rule name
+	if kind, ok := gapicRules[language]; ok {
+		if rules := file.Rules(kind); len(rules) > 0 {
+			return extractAPIConfig(rules[0]), nil
+		}
+	}
 	ruleSuffix := fmt.Sprintf("_%s_gapic", language)
 	for _, rule := range file.Rules("") {
 		if strings.HasSuffix(rule.Name(), ruleSuffix) {
@@ -38,6 +44,25 @@ func ParseBuildFile(buildPath string, language string) (*state.API, error) {
 	return nil, nil
 }
 
+// gapicRules are the GAPIC library rule kinds, keyed by language.
+var gapicRules = map[string]string{
+	"dart":   "dart_gapic_library",
+	"go":     "go_gapic_library",
+	"python": "py_gapic_library",
+	"rust":   "rust_gapic_library",
+}
+
+// PythonPackage returns the package name set by the warehouse-package-name
+// option of a py_gapic_library rule, or an empty string if it is not set.
+func PythonPackage(api *state.API) string {
+	for _, arg := range api.OptArgs {
+		if name, ok := strings.CutPrefix(arg, "warehouse-package-name="); ok {
+			return name
+		}
+	}
+	return ""
+}
+
 // extractAPIConfig extracts API configuration from a BUILD rule
 func extractAPIConfig(rule *build.Rule) *state.API {
 	api := &state.API{}
@@ -0,0 +1,132 @@
+package bazel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+const secretmanagerBuild = `
+load("@com_google_googleapis_imports//:imports.bzl", "dart_gapic_library", "go_gapic_library", "py_gapic_library", "rust_gapic_library")
+
+go_gapic_library(
+    name = "secretmanager_go_gapic",
+    grpc_service_config = "secretmanager_grpc_service_config.json",
+    importpath = "cloud.google.com/go/secretmanager/apiv1;secretmanager",
+    rest_numeric_enums = True,
+    service_yaml = "secretmanager_v1.yaml",
+    transport = "grpc+rest",
+)
+
+py_gapic_library(
+    name = "secretmanager_py_gapic",
+    srcs = [":secretmanager_proto"],
+    grpc_service_config = "secretmanager_grpc_service_config.json",
+    opt_args = [
+        "warehouse-package-name=google-cloud-secret-manager",
+        "python-gapic-namespace=google.cloud",
+        "python-gapic-name=secretmanager",
+    ],
+    rest_numeric_enums = True,
+    service_yaml = "secretmanager_v1.yaml",
+    transport = "grpc+rest",
+)
+
+rust_gapic_library(
+    name = "secretmanager_rust_gapic",
+    srcs = [":secretmanager_proto"],
+    grpc_service_config = "secretmanager_grpc_service_config.json",
+    service_yaml = "secretmanager_v1.yaml",
+    transport = "grpc+rest",
+)
+
+dart_gapic_library(
+    name = "secretmanager_dart_gapic",
+    srcs = [":secretmanager_proto"],
+    service_yaml = "secretmanager_v1.yaml",
+    transport = "rest",
+)
+`
+
+func writeBuild(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "BUILD.bazel")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseBuildFile(t *testing.T) {
+	path := writeBuild(t, secretmanagerBuild)
+	for _, test := range []struct {
+		language string
+		want     *state.API
+	}{
+		{
+			language: "go",
+			want: &state.API{
+				GrpcServiceConfig: "secretmanager_grpc_service_config.json",
+				ServiceYaml:       "secretmanager_v1.yaml",
+				Transport:         "grpc+rest",
+				RestNumericEnums:  true,
+			},
+		},
+		{
+			language: "python",
+			want: &state.API{
+				GrpcServiceConfig: "secretmanager_grpc_service_config.json",
+				ServiceYaml:       "secretmanager_v1.yaml",
+				Transport:         "grpc+rest",
+				RestNumericEnums:  true,
+				OptArgs: []string{
+					"warehouse-package-name=google-cloud-secret-manager",
+					"python-gapic-namespace=google.cloud",
+					"python-gapic-name=secretmanager",
+				},
+			},
+		},
+		{
+			language: "rust",
+			want: &state.API{
+				GrpcServiceConfig: "secretmanager_grpc_service_config.json",
+				ServiceYaml:       "secretmanager_v1.yaml",
+				Transport:         "grpc+rest",
+			},
+		},
+		{
+			language: "dart",
+			want: &state.API{
+				ServiceYaml: "secretmanager_v1.yaml",
+				Transport:   "rest",
+			},
+		},
+		{language: "java"},
+	} {
+		t.Run(test.language, func(t *testing.T) {
+			got, err := ParseBuildFile(path, test.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ParseBuildFile() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPythonPackage(t *testing.T) {
+	api, err := ParseBuildFile(writeBuild(t, secretmanagerBuild), "python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := PythonPackage(api), "google-cloud-secret-manager"; got != want {
+		t.Errorf("PythonPackage() = %q, want %q", got, want)
+	}
+	if got := PythonPackage(&state.API{}); got != "" {
+		t.Errorf("PythonPackage() = %q, want empty", got)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
+	"github.com/julieqiu/exp/librarian/internal/yamlfile"
 	"gopkg.in/yaml.v3"
 )
 
@@ -26,26 +26,33 @@ type GenerateConfig struct {
 	Googleapis *RepoConfig      `yaml:"googleapis,omitempty"`
 	Discovery  *RepoConfig      `yaml:"discovery,omitempty"`
 	Dir        string           `yaml:"dir,omitempty"`
+	Runtime    string           `yaml:"runtime,omitempty"`   // "docker" (default), "podman", or "local"
+	Generator  string           `yaml:"generator,omitempty"` // Generator executable for the local runtime
 }
 
 type ContainerConfig struct {
-	Image string `yaml:"image,omitempty"`
-	Tag   string `yaml:"tag,omitempty"`
+	Image  string `yaml:"image,omitempty"`
+	Tag    string `yaml:"tag,omitempty"`
+	Digest string `yaml:"digest,omitempty"` // Manifest digest of Tag, recorded by config update
 }
 
 type RepoConfig struct {
-	Repo string `yaml:"repo"`
-	Ref  string `yaml:"ref,omitempty"`
+	Repo   string `yaml:"repo"`
+	Ref    string `yaml:"ref,omitempty"`
+	SHA256 string `yaml:"sha256,omitempty"` // Checksum of the tarball at Ref
 }
 
 type ReleaseConfig struct {
 	TagFormat      string          `yaml:"tag_format"`
+	Remote         string          `yaml:"remote,omitempty"`      // Git remote that tags are pushed to (default "origin")
+	GitHubRepo     string          `yaml:"github_repo,omitempty"` // "owner/repo" (default derived from the remote URL)
 	BranchPatterns []BranchPattern `yaml:"branch_patterns,omitempty"`
 }
 
 type BranchPattern struct {
-	Pattern    string `yaml:"pattern"`     // "main", "release/*", etc.
-	Prerelease string `yaml:"prerelease"`  // "", "rc", "alpha", etc.
+	Pattern     string `yaml:"pattern"`               // "main", "release/*", etc.
+	Prerelease  string `yaml:"prerelease"`            // "", "rc", "alpha", etc.
+	Maintenance bool   `yaml:"maintenance,omitempty"` // Releases patches of the series in the branch name, e.g. release/v1.x
 }
 
 const (
@@ -69,161 +76,22 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
-// Save writes the config to .librarian/config.yaml.
+// Save writes the config to .librarian/config.yaml. Only the fields that
+// changed are rewritten, so comments and key order in an existing file are
+// kept.
 func (c *Config) Save() error {
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return fmt.Errorf("failed to create .librarian directory: %w", err)
 	}
 
-	data, err := yaml.Marshal(c)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
 	path := filepath.Join(configDir, configFile)
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := yamlfile.Write(path, c); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
-// Set updates a configuration value.
-func (c *Config) Set(key, value string) error {
-	switch key {
-	case "librarian.version":
-		c.Librarian.Version = value
-	case "librarian.language":
-		c.Librarian.Language = value
-	case "release.tag_format":
-		if c.Release == nil {
-			c.Release = &ReleaseConfig{}
-		}
-		c.Release.TagFormat = value
-	case "generate.container.image":
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		if c.Generate.Container == nil {
-			c.Generate.Container = &ContainerConfig{}
-		}
-		c.Generate.Container.Image = value
-	case "generate.container.tag":
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		if c.Generate.Container == nil {
-			c.Generate.Container = &ContainerConfig{}
-		}
-		c.Generate.Container.Tag = value
-	case "generate.container":
-		// Syntactic sugar: parse "image:tag"
-		parts := strings.Split(value, ":")
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		if c.Generate.Container == nil {
-			c.Generate.Container = &ContainerConfig{}
-		}
-		c.Generate.Container.Image = parts[0]
-		if len(parts) > 1 {
-			c.Generate.Container.Tag = parts[1]
-		}
-	case "generate.googleapis.repo":
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		if c.Generate.Googleapis == nil {
-			c.Generate.Googleapis = &RepoConfig{}
-		}
-		c.Generate.Googleapis.Repo = value
-	case "generate.googleapis.ref":
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		if c.Generate.Googleapis == nil {
-			c.Generate.Googleapis = &RepoConfig{}
-		}
-		c.Generate.Googleapis.Ref = value
-	case "generate.discovery.repo":
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		if c.Generate.Discovery == nil {
-			c.Generate.Discovery = &RepoConfig{}
-		}
-		c.Generate.Discovery.Repo = value
-	case "generate.discovery.ref":
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		if c.Generate.Discovery == nil {
-			c.Generate.Discovery = &RepoConfig{}
-		}
-		c.Generate.Discovery.Ref = value
-	case "generate.dir":
-		if c.Generate == nil {
-			c.Generate = &GenerateConfig{}
-		}
-		c.Generate.Dir = value
-	default:
-		return fmt.Errorf("unknown config key: %s", key)
-	}
-	return nil
-}
-
-// Get retrieves a configuration value.
-func (c *Config) Get(key string) (string, error) {
-	switch key {
-	case "librarian.version":
-		return c.Librarian.Version, nil
-	case "librarian.language":
-		return c.Librarian.Language, nil
-	case "release.tag_format":
-		if c.Release != nil {
-			return c.Release.TagFormat, nil
-		}
-		return "", nil
-	case "generate.container.image":
-		if c.Generate != nil && c.Generate.Container != nil {
-			return c.Generate.Container.Image, nil
-		}
-		return "", nil
-	case "generate.container.tag":
-		if c.Generate != nil && c.Generate.Container != nil {
-			return c.Generate.Container.Tag, nil
-		}
-		return "", nil
-	case "generate.googleapis.repo":
-		if c.Generate != nil && c.Generate.Googleapis != nil {
-			return c.Generate.Googleapis.Repo, nil
-		}
-		return "", nil
-	case "generate.googleapis.ref":
-		if c.Generate != nil && c.Generate.Googleapis != nil {
-			return c.Generate.Googleapis.Ref, nil
-		}
-		return "", nil
-	case "generate.discovery.repo":
-		if c.Generate != nil && c.Generate.Discovery != nil {
-			return c.Generate.Discovery.Repo, nil
-		}
-		return "", nil
-	case "generate.discovery.ref":
-		if c.Generate != nil && c.Generate.Discovery != nil {
-			return c.Generate.Discovery.Ref, nil
-		}
-		return "", nil
-	case "generate.dir":
-		if c.Generate != nil {
-			return c.Generate.Dir, nil
-		}
-		return "", nil
-	default:
-		return "", fmt.Errorf("unknown config key: %s", key)
-	}
-}
-
 // GoogleapisURL returns the full URL for the googleapis archive.
 func (c *Config) GoogleapisURL() string {
 	if c.Generate == nil || c.Generate.Googleapis == nil {
@@ -240,15 +108,29 @@ func (c *Config) DiscoveryURL() string {
 	return fmt.Sprintf("https://%s/archive/%s.tar.gz", c.Generate.Discovery.Repo, c.Generate.Discovery.Ref)
 }
 
-// ContainerImage returns the full container image with tag.
+// ContainerImage returns the full container image with tag, and digest if
+// one is pinned.
 func (c *Config) ContainerImage() string {
 	if c.Generate == nil || c.Generate.Container == nil {
 		return ""
 	}
+	image := c.Generate.Container.Image
 	if c.Generate.Container.Tag != "" {
-		return fmt.Sprintf("%s:%s", c.Generate.Container.Image, c.Generate.Container.Tag)
+		image += ":" + c.Generate.Container.Tag
+	}
+	if c.Generate.Container.Digest != "" {
+		image += "@" + c.Generate.Container.Digest
+	}
+	return image
+}
+
+// ReleaseRemote returns the git remote that release tags are pushed to, with
+// a default of "origin".
+func (c *Config) ReleaseRemote() string {
+	if c.Release != nil && c.Release.Remote != "" {
+		return c.Release.Remote
 	}
-	return c.Generate.Container.Image
+	return "origin"
 }
 
 // Dir returns the generation directory with a default of "generated".
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/julieqiu/exp/librarian/internal/yamlfile"
+	"gopkg.in/yaml.v3"
+)
+
+// A key is a dotted path to a configuration value, such as
+// generate.container.tag or release.branch_patterns[0].prerelease. Each
+// element names a field by its yaml tag and may index into a list.
+type key []keyElem
+
+type keyElem struct {
+	name  string
+	index int // -1 if the element does not index into a list
+}
+
+var keyElemRegex = regexp.MustCompile(`^([a-z0-9_]+)(?:\[([0-9]+)\])?$`)
+
+func parseKey(s string) (key, error) {
+	var k key
+	for _, part := range strings.Split(s, ".") {
+		m := keyElemRegex.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("invalid config key: %s", s)
+		}
+		elem := keyElem{name: m[1], index: -1}
+		if m[2] != "" {
+			elem.index, _ = strconv.Atoi(m[2])
+		}
+		k = append(k, elem)
+	}
+	return k, nil
+}
+
+// schema returns the key with its list indexes replaced by [], such as
+// release.branch_patterns[].prerelease.
+func (k key) schema() string {
+	var parts []string
+	for _, e := range k {
+		if e.index >= 0 {
+			parts = append(parts, e.name+"[]")
+		} else {
+			parts = append(parts, e.name)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// validators check the values of keys whose type alone does not describe
+// the values they accept, keyed by schema.
+var validators = map[string]func(string) error{
+	"generate.runtime": func(v string) error {
+		switch v {
+		case "docker", "podman", "local":
+			return nil
+		}
+		return fmt.Errorf("must be docker, podman, or local")
+	},
+	"release.tag_format": func(v string) error {
+		if !strings.Contains(v, "{version}") {
+			return fmt.Errorf("must contain {version}")
+		}
+		return nil
+	},
+	"release.branch_patterns[].pattern": func(v string) error {
+		if _, err := path.Match(v, ""); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		return nil
+	},
+	"release.branch_patterns[].prerelease": func(v string) error {
+		if !prereleaseRegex.MatchString(v) {
+			return fmt.Errorf("must be a prerelease identifier such as rc or alpha")
+		}
+		return nil
+	},
+}
+
+var prereleaseRegex = regexp.MustCompile(`^[0-9A-Za-z-]*$`)
+
+// fieldType returns the type of the value named by k, checking k against
+// the Config schema.
+func fieldType(k key) (reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	for i, e := range k {
+		f, ok := fieldByTag(t, e.name)
+		if !ok {
+			return nil, fmt.Errorf("unknown config key: %s", k[:i+1].schema())
+		}
+		t = f.Type
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if e.index >= 0 {
+			if t.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("config key %s is not a list", k[:i+1].schema())
+			}
+			t = t.Elem()
+		}
+		if i < len(k)-1 && t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("unknown config key: %s", k[:i+2].schema())
+		}
+	}
+	return t, nil
+}
+
+// fieldByTag returns the field of the struct type t whose yaml tag is name.
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// lookup returns the value named by k. If create is true, missing sections
+// and list elements up to one past the end of a list are allocated;
+// otherwise an invalid value is returned for a missing section.
+func (c *Config) lookup(k key, create bool) (reflect.Value, error) {
+	v := reflect.ValueOf(c).Elem()
+	for i, e := range k {
+		if !deref(&v, create) {
+			return reflect.Value{}, nil
+		}
+		f, _ := fieldByTag(v.Type(), e.name)
+		v = v.FieldByIndex(f.Index)
+		if e.index < 0 {
+			continue
+		}
+		if !deref(&v, create) {
+			return reflect.Value{}, nil
+		}
+		switch {
+		case e.index < v.Len():
+		case e.index == v.Len() && create:
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		default:
+			return reflect.Value{}, fmt.Errorf("config key %s: index %d out of range", k[:i+1].schema(), e.index)
+		}
+		v = v.Index(e.index)
+	}
+	deref(&v, create)
+	return v, nil
+}
+
+// deref follows v through a pointer, allocating a nil pointer if create is
+// true. It reports whether v is valid.
+func deref(v *reflect.Value, create bool) bool {
+	if v.Kind() != reflect.Pointer {
+		return true
+	}
+	if v.IsNil() {
+		if !create {
+			return false
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	*v = v.Elem()
+	return true
+}
+
+// Get retrieves a configuration value. Scalar values are returned as
+// strings, and sections and lists as YAML. Unset values are returned as an
+// empty string.
+func (c *Config) Get(s string) (string, error) {
+	k, err := parseKey(s)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fieldType(k); err != nil {
+		return "", err
+	}
+	v, err := c.lookup(k, false)
+	if err != nil || !v.IsValid() {
+		return "", err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+	}
+	data, err := yaml.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// Set updates a configuration value, converting value to the type of the
+// key. A list element may be added by indexing one past the end of the list.
+//
+// As a shorthand, generate.container accepts "image:tag". The tag follows the
+// last colon after the final slash, so an image in a registry with a port,
+// such as localhost:5000/generator:v1, is split correctly.
+func (c *Config) Set(s, value string) error {
+	if s == "generate.container" {
+		image, tag := value, ""
+		if i := strings.LastIndex(value, ":"); i > strings.LastIndex(value, "/") {
+			image, tag = value[:i], value[i+1:]
+		}
+		if err := c.Set("generate.container.image", image); err != nil || tag == "" {
+			return err
+		}
+		return c.Set("generate.container.tag", tag)
+	}
+	k, err := parseKey(s)
+	if err != nil {
+		return err
+	}
+	t, err := fieldType(k)
+	if err != nil {
+		return err
+	}
+	if validate, ok := validators[k.schema()]; ok {
+		if err := validate(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", s, err)
+		}
+	}
+	var x any
+	switch t.Kind() {
+	case reflect.String:
+		x = value
+	case reflect.Bool:
+		if x, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %q is not a boolean", s, value)
+		}
+	case reflect.Int:
+		if x, err = strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %q is not an integer", s, value)
+		}
+	default:
+		return fmt.Errorf("config key %s is a section; set one of its fields instead", s)
+	}
+	v, err := c.lookup(k, true)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(x).Convert(t))
+	return nil
+}
+
+// Edit sets key to value in .librarian/config.yaml, keeping the comments
+// and key order of the rest of the file.
+func Edit(key, value string) error {
+	return editFile(filepath.Join(configDir, configFile), key, value)
+}
+
+func editFile(name, s, value string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := cfg.Set(s, value); err != nil {
+		return err
+	}
+	if err := yamlfile.Write(name, &cfg); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
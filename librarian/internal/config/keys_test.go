@@ -0,0 +1,201 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGet(t *testing.T) {
+	cfg := &Config{
+		Librarian: LibrarianConfig{Version: "v0.5.0"},
+		Generate: &GenerateConfig{
+			Container: &ContainerConfig{Image: "python-gen", Tag: "v1.2.0"},
+		},
+		Release: &ReleaseConfig{
+			TagFormat: "{name}-v{version}",
+			BranchPatterns: []BranchPattern{
+				{Pattern: "main"},
+				{Pattern: "preview", Prerelease: "rc"},
+			},
+		},
+	}
+	for _, test := range []struct {
+		key  string
+		want string
+	}{
+		{"librarian.version", "v0.5.0"},
+		{"generate.container.tag", "v1.2.0"},
+		{"generate.googleapis.ref", ""},
+		{"release.branch_patterns[1].prerelease", "rc"},
+		{"release.branch_patterns[0]", "pattern: main\nprerelease: \"\""},
+		{"generate.container", "image: python-gen\ntag: v1.2.0"},
+	} {
+		t.Run(test.key, func(t *testing.T) {
+			got, err := cfg.Get(test.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("Get(%q) = %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetError(t *testing.T) {
+	cfg := &Config{Release: &ReleaseConfig{}}
+	for _, test := range []struct {
+		key  string
+		want string
+	}{
+		{"generate.container.name", "unknown config key: generate.container.name"},
+		{"generate.dir.name", "unknown config key: generate.dir.name"},
+		{"generate.dir[0]", "config key generate.dir[] is not a list"},
+		{"release.branch_patterns[2].pattern", "index 2 out of range"},
+		{"release..tag_format", "invalid config key"},
+	} {
+		t.Run(test.key, func(t *testing.T) {
+			_, err := cfg.Get(test.key)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Get(%q) = %v, want error containing %q", test.key, err, test.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	cfg := &Config{}
+	for _, kv := range [][2]string{
+		{"generate.container", "python-gen:v1.2.0"},
+		{"generate.runtime", "podman"},
+		{"release.tag_format", "v{version}"},
+		{"release.branch_patterns[0].pattern", "main"},
+		{"release.branch_patterns[1].pattern", "preview"},
+		{"release.branch_patterns[1].prerelease", "rc"},
+	} {
+		if err := cfg.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%q, %q): %v", kv[0], kv[1], err)
+		}
+	}
+	want := &Config{
+		Generate: &GenerateConfig{
+			Container: &ContainerConfig{Image: "python-gen", Tag: "v1.2.0"},
+			Runtime:   "podman",
+		},
+		Release: &ReleaseConfig{
+			TagFormat: "v{version}",
+			BranchPatterns: []BranchPattern{
+				{Pattern: "main"},
+				{Pattern: "preview", Prerelease: "rc"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("Set() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetContainer(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		want  *ContainerConfig
+	}{
+		{"python-gen:v1.2.0", &ContainerConfig{Image: "python-gen", Tag: "v1.2.0"}},
+		{"python-gen", &ContainerConfig{Image: "python-gen"}},
+		{"localhost:5000/img:tag", &ContainerConfig{Image: "localhost:5000/img", Tag: "tag"}},
+		{"localhost:5000/img", &ContainerConfig{Image: "localhost:5000/img"}},
+	} {
+		t.Run(test.value, func(t *testing.T) {
+			cfg := &Config{}
+			if err := cfg.Set("generate.container", test.value); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, cfg.Generate.Container); diff != "" {
+				t.Errorf("Set(%q) mismatch (-want +got):\n%s", test.value, diff)
+			}
+		})
+	}
+}
+
+func TestSetError(t *testing.T) {
+	for _, test := range []struct {
+		key, value string
+		want       string
+	}{
+		{"generate.runtime", "kubernetes", "must be docker, podman, or local"},
+		{"release.tag_format", "v1", "must contain {version}"},
+		{"release.branch_patterns[0].pattern", "release/[", "invalid pattern"},
+		{"release.branch_patterns[0].prerelease", "rc.1", "must be a prerelease identifier"},
+		{"release.branch_patterns[1].pattern", "main", "index 1 out of range"},
+		{"release", "x", "is a section"},
+		{"release.branches", "x", "unknown config key: release.branches"},
+	} {
+		t.Run(test.key, func(t *testing.T) {
+			err := (&Config{}).Set(test.key, test.value)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Set(%q, %q) = %v, want error containing %q", test.key, test.value, err, test.want)
+			}
+		})
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `# Managed by librarian.
+librarian:
+  version: v0.5.0 # pinned
+  language: python
+
+generate:
+  container:
+    image: python-gen
+    tag: v1.2.0 # updated by config update
+
+release:
+  tag_format: '{name}-v{version}'
+  branch_patterns:
+    - pattern: main
+      prerelease: ""
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range [][2]string{
+		{"generate.container.tag", "v1.3.0"},
+		{"release.tag_format", "{id}-v{version}"},
+		{"release.branch_patterns[1].pattern", "preview"},
+		{"generate.googleapis.ref", "abc123"},
+	} {
+		if err := editFile(path, kv[0], kv[1]); err != nil {
+			t.Fatalf("editFile(%q, %q): %v", kv[0], kv[1], err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Managed by librarian.
+librarian:
+  version: v0.5.0 # pinned
+  language: python
+generate:
+  container:
+    image: python-gen
+    tag: v1.3.0 # updated by config update
+  googleapis:
+    ref: abc123
+release:
+  tag_format: '{id}-v{version}'
+  branch_patterns:
+    - pattern: main
+      prerelease: ""
+    - pattern: preview
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,92 @@
+// Package container runs language generators using the librarian container
+// contract.
+//
+// A generator is invoked with a command (such as generate) and a set of
+// mounted directories. Requests are passed as JSON files in the /librarian
+// mount and generated files are written to the /output mount. Generators are
+// usually run in a container with Docker or Podman, but a Runtime may also run
+// them directly on the host using the same protocol.
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// GenerateRequest represents the generate-request.json structure.
+type GenerateRequest struct {
+	ID            string   `json:"id"`
+	Version       string   `json:"version,omitempty"`
+	APIs          []API    `json:"apis"`
+	SourceRoots   []string `json:"source_roots,omitempty"`
+	PreserveRegex []string `json:"preserve_regex,omitempty"`
+	RemoveRegex   []string `json:"remove_regex,omitempty"`
+}
+
+// API represents an API to generate.
+type API struct {
+	Path          string `json:"path"`
+	ServiceConfig string `json:"service_config,omitempty"`
+}
+
+// GenerateDirs holds the host directories mounted into the container for the
+// generate command.
+type GenerateDirs struct {
+	Source string // googleapis checkout, mounted at /source
+	Input  string // generator input, mounted at /input
+	Output string // output directory, mounted at /output
+}
+
+// Generate runs the generate command of the given image with rt.
+//
+// The request is written to a temporary directory mounted at /librarian. The
+// output directory must exist and should be empty. The generator's output is
+// written to log, or to the standard output and error streams if log is nil.
+func Generate(ctx context.Context, rt Runtime, image string, req *GenerateRequest, dirs *GenerateDirs, log io.Writer) error {
+	librarianDir, err := os.MkdirTemp("", "librarian-request-")
+	if err != nil {
+		return fmt.Errorf("failed to create request directory: %w", err)
+	}
+	defer os.RemoveAll(librarianDir)
+
+	if err := writeRequest(librarianDir, "generate-request.json", req); err != nil {
+		return err
+	}
+
+	inputDir := dirs.Input
+	if inputDir == "" {
+		// The container contract requires /input to exist even if the
+		// repository has no generator input.
+		inputDir = filepath.Join(librarianDir, "input")
+		if err := os.MkdirAll(inputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create input directory: %w", err)
+		}
+	}
+
+	return rt.Run(ctx, &Invocation{
+		Image:   image,
+		Command: "generate",
+		Mounts: []Mount{
+			{Name: "librarian", Dir: librarianDir, ReadOnly: true},
+			{Name: "input", Dir: inputDir, ReadOnly: true},
+			{Name: "output", Dir: dirs.Output},
+			{Name: "source", Dir: dirs.Source, ReadOnly: true},
+		},
+	}, log)
+}
+
+// writeRequest writes v as indented JSON to name in dir.
+func writeRequest(dir, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Runtime runs a generator command.
+type Runtime interface {
+	// Run runs inv, writing the generator's output to log, or to the
+	// standard output and error streams if log is nil.
+	Run(ctx context.Context, inv *Invocation, log io.Writer) error
+}
+
+// Invocation is a single run of a generator command.
+type Invocation struct {
+	// Image is the generator container image, including the tag.
+	Image string
+
+	// Command is the container contract command, such as "generate",
+	// "configure", "release-stage", or "build".
+	Command string
+
+	// Mounts are the directories the generator reads and writes.
+	Mounts []Mount
+}
+
+// Mount is a host directory made available to a generator. In a container,
+// the directory is mounted at /<Name>. The generator is passed the flag
+// --<Name> with the path of the directory.
+type Mount struct {
+	Name     string // "librarian", "input", "output", "source", or "repo"
+	Dir      string
+	ReadOnly bool
+}
+
+// Runtime names accepted by NewRuntime.
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+	RuntimeLocal  = "local"
+)
+
+// NewRuntime returns the runtime with the given name. An empty name selects
+// Docker. For the local runtime, generator is the generator executable, or
+// handlers run commands in-process if generator is empty.
+func NewRuntime(name, generator string, handlers map[string]Handler) (Runtime, error) {
+	switch name {
+	case "", RuntimeDocker:
+		return &Engine{Program: "docker"}, nil
+	case RuntimePodman:
+		return &Engine{Program: "podman"}, nil
+	case RuntimeLocal:
+		if generator == "" && len(handlers) == 0 {
+			return nil, fmt.Errorf("local runtime requires a generator executable")
+		}
+		return &Local{Generator: generator, Handlers: handlers}, nil
+	default:
+		return nil, fmt.Errorf("unknown runtime %q (want %s, %s, or %s)", name, RuntimeDocker, RuntimePodman, RuntimeLocal)
+	}
+}
+
+// Engine runs generators in containers using a Docker-compatible command line
+// tool, such as docker or podman.
+type Engine struct {
+	// Program is the name or path of the tool. Podman is recognized by the
+	// base name, so a path such as /usr/bin/podman is run as podman.
+	Program string
+}
+
+// Run implements Runtime.
+func (e *Engine) Run(ctx context.Context, inv *Invocation, log io.Writer) error {
+	if inv.Image == "" {
+		return fmt.Errorf("container image is required")
+	}
+	args := []string{"run", "--rm"}
+	if filepath.Base(e.Program) == "podman" {
+		// Rootless podman maps the host user to root in the container;
+		// keep-id preserves the host user so output files are owned by it.
+		args = append(args, "--userns=keep-id")
+	} else {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+	for _, m := range inv.Mounts {
+		v := m.Dir + ":/" + m.Name
+		if m.ReadOnly {
+			v += ":ro"
+		}
+		args = append(args, "-v", v)
+	}
+	args = append(args, inv.Image, inv.Command)
+	for _, m := range inv.Mounts {
+		args = append(args, "--"+m.Name, "/"+m.Name)
+	}
+	return runCommand(ctx, log, inv.Image, e.Program, args...)
+}
+
+// LocalDigest returns the digest of image as recorded by the engine for the
+// copy it has pulled, without contacting the registry. It returns an empty
+// string if the engine has no pulled copy of image or records no digest for
+// it, for example because it was built locally.
+func (e *Engine) LocalDigest(ctx context.Context, image string) string {
+	cmd := exec.CommandContext(ctx, e.Program, "image", "inspect", "--format", "{{index .RepoDigests 0}}", image)
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	_, digest, ok := strings.Cut(strings.TrimSpace(string(output)), "@")
+	if !ok {
+		return ""
+	}
+	return digest
+}
+
+// Handler runs a generator command in-process. dirs maps each mount name to
+// its host directory.
+type Handler func(ctx context.Context, dirs map[string]string) error
+
+// Local runs generators directly on the host, without a container. The
+// generator reads requests from and writes responses to the same directories
+// as it would in a container, so the protocol is unchanged.
+type Local struct {
+	// Generator is the generator executable. It is invoked like the
+	// container entrypoint, with host paths in place of mount points:
+	//
+	//	<generator> generate --librarian <dir> --input <dir> ...
+	Generator string
+
+	// Handlers run commands in-process, keyed by command name. They are
+	// used when Generator is empty.
+	Handlers map[string]Handler
+}
+
+// Run implements Runtime. Image is ignored.
+func (l *Local) Run(ctx context.Context, inv *Invocation, log io.Writer) error {
+	if l.Generator != "" {
+		args := []string{inv.Command}
+		for _, m := range inv.Mounts {
+			args = append(args, "--"+m.Name, m.Dir)
+		}
+		return runCommand(ctx, log, l.Generator, l.Generator, args...)
+	}
+	h, ok := l.Handlers[inv.Command]
+	if !ok {
+		return fmt.Errorf("local generator does not support %s (supported: %v)", inv.Command, l.commands())
+	}
+	dirs := make(map[string]string)
+	for _, m := range inv.Mounts {
+		dirs[m.Name] = m.Dir
+	}
+	if err := h(ctx, dirs); err != nil {
+		return fmt.Errorf("local %s failed: %w", inv.Command, err)
+	}
+	return nil
+}
+
+func (l *Local) commands() []string {
+	var names []string
+	for name := range l.Handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runCommand runs program with args. Output is written to log, or to the
+// standard output and error streams if log is nil. name identifies the
+// generator in errors.
+func runCommand(ctx context.Context, log io.Writer, name, program string, args ...string) error {
+	cmd := exec.CommandContext(ctx, program, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if log != nil {
+		cmd.Stdout, cmd.Stderr = log, log
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	return nil
+}
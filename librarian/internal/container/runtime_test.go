@@ -0,0 +1,150 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeProgram writes an executable that records its arguments, one per line,
+// in the returned file.
+func fakeProgram(t *testing.T) (program, argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	program = filepath.Join(dir, "fake")
+	argsFile = filepath.Join(dir, "args")
+	script := fmt.Sprintf("#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done > %q\n", argsFile)
+	if err := os.WriteFile(program, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return program, argsFile
+}
+
+func readArgs(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+var testInvocation = &Invocation{
+	Image:   "gcr.io/generator:v1",
+	Command: "generate",
+	Mounts: []Mount{
+		{Name: "librarian", Dir: "/tmp/request", ReadOnly: true},
+		{Name: "output", Dir: "/tmp/output"},
+	},
+}
+
+func TestEngine(t *testing.T) {
+	program, argsFile := fakeProgram(t)
+	if err := (&Engine{Program: program}).Run(context.Background(), testInvocation, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"run", "--rm",
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-v", "/tmp/request:/librarian:ro",
+		"-v", "/tmp/output:/output",
+		"gcr.io/generator:v1", "generate",
+		"--librarian", "/librarian",
+		"--output", "/output",
+	}
+	if diff := cmp.Diff(want, readArgs(t, argsFile)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEnginePodmanPath(t *testing.T) {
+	fake, argsFile := fakeProgram(t)
+	program := filepath.Join(filepath.Dir(fake), "podman")
+	if err := os.Rename(fake, program); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Engine{Program: program}).Run(context.Background(), testInvocation, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readArgs(t, argsFile)[2]; got != "--userns=keep-id" {
+		t.Errorf("podman run flag = %q, want %q", got, "--userns=keep-id")
+	}
+}
+
+func TestLocalGenerator(t *testing.T) {
+	program, argsFile := fakeProgram(t)
+	if err := (&Local{Generator: program}).Run(context.Background(), testInvocation, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"generate",
+		"--librarian", "/tmp/request",
+		"--output", "/tmp/output",
+	}
+	if diff := cmp.Diff(want, readArgs(t, argsFile)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalHandler(t *testing.T) {
+	// The handler receives the same request file a container would.
+	var got *GenerateRequest
+	rt := &Local{Handlers: map[string]Handler{
+		"generate": func(ctx context.Context, dirs map[string]string) error {
+			data, err := os.ReadFile(filepath.Join(dirs["librarian"], "generate-request.json"))
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(data, &got)
+		},
+	}}
+	req := &GenerateRequest{ID: "secretmanager", APIs: []API{{Path: "google/cloud/secretmanager/v1"}}}
+	dirs := &GenerateDirs{Source: t.TempDir(), Output: t.TempDir()}
+	if err := Generate(context.Background(), rt, "", req, dirs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(req, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	inv := &Invocation{Command: "configure"}
+	if err := rt.Run(context.Background(), inv, nil); err == nil {
+		t.Error("Run() with unsupported command succeeded, want error")
+	}
+}
+
+func TestNewRuntime(t *testing.T) {
+	for _, test := range []struct {
+		name, generator string
+		want            Runtime
+	}{
+		{"", "", &Engine{Program: "docker"}},
+		{"docker", "", &Engine{Program: "docker"}},
+		{"podman", "", &Engine{Program: "podman"}},
+		{"local", "/bin/generator", &Local{Generator: "/bin/generator"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewRuntime(test.name, test.generator, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewRuntimeError(t *testing.T) {
+	for _, name := range []string{"local", "kubernetes"} {
+		if _, err := NewRuntime(name, "", nil); err == nil {
+			t.Errorf("NewRuntime(%q) succeeded, want error", name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package contents computes the files that ship in an artifact's release
+// package, such as a Go module zip or a Python sdist, and the files that the
+// package manifest refers to.
+package contents
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// List returns the files in dir that the packaging tools of language would
+// include in a release package, relative to dir and using forward slashes.
+// The result is sorted.
+//
+// The rules follow each language's packaging tool:
+//
+//   - go: the files of a module zip. Nested modules, vendored packages, and
+//     version control directories are left out.
+//   - rust: the files of `cargo package`. Nested packages, the target
+//     directory, and hidden files are left out.
+//   - python, dart, and other languages: every file except hidden files and
+//     build output.
+func List(dir, language string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() {
+			skip, err := skipDir(p, rel, language)
+			if err != nil {
+				return err
+			}
+			if skip {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symbolic links and other irregular files are not packaged.
+		if !d.Type().IsRegular() || skipFile(rel, language) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// manifests are the package manifests of each language, which are always
+// packaged.
+var manifests = map[string][]string{
+	"go":     {"go.mod"},
+	"python": {"pyproject.toml", "setup.py", "setup.cfg"},
+	"rust":   {"Cargo.toml"},
+	"dart":   {"pubspec.yaml"},
+}
+
+// skipDir reports whether the directory at p, with path rel relative to the
+// artifact directory, is left out of the package.
+func skipDir(p, rel, language string) (bool, error) {
+	name := path.Base(rel)
+	switch language {
+	case "go":
+		if name == ".git" || name == ".hg" || name == ".svn" || name == ".bzr" {
+			return true, nil
+		}
+		return isNestedPackage(p, "go.mod")
+	case "rust":
+		if strings.HasPrefix(name, ".") || rel == "target" {
+			return true, nil
+		}
+		return isNestedPackage(p, "Cargo.toml")
+	default:
+		return strings.HasPrefix(name, ".") || name == "__pycache__" || name == "build" ||
+			name == "dist" || strings.HasSuffix(name, ".egg-info") || name == "node_modules", nil
+	}
+}
+
+// isNestedPackage reports whether dir contains the manifest file, and so
+// holds a separate package.
+func isNestedPackage(dir, manifest string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dir, manifest))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// skipFile reports whether the file with path rel relative to the artifact
+// directory is left out of the package.
+func skipFile(rel, language string) bool {
+	name := path.Base(rel)
+	switch language {
+	case "go":
+		return isVendoredPackage(rel)
+	case "python":
+		return strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".pyc")
+	default:
+		return strings.HasPrefix(name, ".")
+	}
+}
+
+// isVendoredPackage reports whether the file is part of a package in a vendor
+// directory. As in the go command, files directly in a vendor directory, such
+// as vendor/modules.txt, are kept.
+func isVendoredPackage(rel string) bool {
+	var i int
+	if strings.HasPrefix(rel, "vendor/") {
+		i = len("vendor/")
+	} else if j := strings.Index(rel, "/vendor/"); j >= 0 {
+		i = j + len("/vendor/")
+	} else {
+		return false
+	}
+	return strings.Contains(rel[i:], "/")
+}
+
+var (
+	// quotedRegex matches a quoted string, such as the readme or
+	// license-file values in pyproject.toml and Cargo.toml, or the file
+	// passed to open() in setup.py.
+	quotedRegex = regexp.MustCompile(`["']([^"'\n]+)["']`)
+
+	// fileDirectiveRegex matches a `file:` directive in setup.cfg, such as
+	// `long_description = file: README.md, CHANGES.md`.
+	fileDirectiveRegex = regexp.MustCompile(`(?m)=\s*file:\s*(.+)$`)
+)
+
+// References returns the files that the package manifests of the artifact in
+// dir refer to, such as the readme or license file, including the manifests
+// themselves. Only paths in files, the files of the artifact, are returned,
+// so strings in a manifest that do not name a file, such as package or
+// directory names, are ignored.
+func References(dir, language string, files []string) ([]string, error) {
+	known := make(map[string]bool)
+	for _, f := range files {
+		known[f] = true
+	}
+	refs := make(map[string]bool)
+	add := func(ref string) {
+		ref = path.Clean(filepath.ToSlash(strings.TrimSpace(ref)))
+		if known[ref] {
+			refs[ref] = true
+		}
+	}
+	for _, name := range manifests[language] {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		refs[name] = true
+		for _, m := range quotedRegex.FindAllStringSubmatch(string(data), -1) {
+			add(m[1])
+		}
+		if name == "setup.cfg" {
+			for _, m := range fileDirectiveRegex.FindAllStringSubmatch(string(data), -1) {
+				for _, f := range strings.Split(m[1], ",") {
+					add(f)
+				}
+			}
+		}
+	}
+	var out []string
+	for ref := range refs {
+		out = append(out, ref)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -0,0 +1,176 @@
+package contents
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	for _, test := range []struct {
+		language string
+		files    []string
+		want     []string
+	}{
+		{
+			language: "go",
+			files: []string{
+				"go.mod",
+				"client.go",
+				".github/CODEOWNERS",
+				".git/HEAD",
+				"internal/gen.go",
+				"testdata/golden.txt",
+				"vendor/modules.txt",
+				"vendor/example.com/dep/dep.go",
+				"apiv2/go.mod",
+				"apiv2/client.go",
+			},
+			want: []string{
+				".github/CODEOWNERS",
+				"client.go",
+				"go.mod",
+				"internal/gen.go",
+				"testdata/golden.txt",
+				"vendor/modules.txt",
+			},
+		},
+		{
+			language: "python",
+			files: []string{
+				"pyproject.toml",
+				"README.rst",
+				".gitignore",
+				".nox/lib/site.py",
+				"google/cloud/secretmanager/__init__.py",
+				"google/cloud/secretmanager/__pycache__/__init__.cpython-312.pyc",
+				"build/lib/google/cloud/secretmanager/__init__.py",
+				"dist/google_cloud_secret_manager-1.0.0.tar.gz",
+				"google_cloud_secret_manager.egg-info/PKG-INFO",
+				"tests/unit/test_client.py",
+			},
+			want: []string{
+				"README.rst",
+				"google/cloud/secretmanager/__init__.py",
+				"pyproject.toml",
+				"tests/unit/test_client.py",
+			},
+		},
+		{
+			language: "rust",
+			files: []string{
+				"Cargo.toml",
+				"src/lib.rs",
+				"target/debug/libgax.rlib",
+				"examples/Cargo.toml",
+				"examples/src/main.rs",
+				".cargo_vcs_info.json",
+			},
+			want: []string{
+				"Cargo.toml",
+				"src/lib.rs",
+			},
+		},
+	} {
+		t.Run(test.language, func(t *testing.T) {
+			dir := t.TempDir()
+			files := make(map[string]string)
+			for _, f := range test.files {
+				files[f] = ""
+			}
+			writeFiles(t, dir, files)
+			got, err := List(dir, test.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("List() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		language string
+		files    map[string]string
+		want     []string
+	}{
+		{
+			name:     "pyproject",
+			language: "python",
+			files: map[string]string{
+				"pyproject.toml": "[project]\nname = \"google-cloud-secret-manager\"\nreadme = \"README.rst\"\nlicense = {file = \"LICENSE\"}\n",
+				"README.rst":     "",
+				"LICENSE":        "",
+				"tests/test.py":  "",
+			},
+			want: []string{"LICENSE", "README.rst", "pyproject.toml"},
+		},
+		{
+			name:     "setup.py and setup.cfg",
+			language: "python",
+			files: map[string]string{
+				"setup.py":       "setuptools.setup(\n    long_description=open('docs/README.md').read(),\n    packages=find_packages(exclude=['tests']),\n)\n",
+				"setup.cfg":      "[metadata]\nlong_description = file: CHANGELOG.md, NOTICE\n",
+				"CHANGELOG.md":   "",
+				"NOTICE":         "",
+				"docs/README.md": "",
+				"tests/test.py":  "",
+			},
+			want: []string{"CHANGELOG.md", "NOTICE", "docs/README.md", "setup.cfg", "setup.py"},
+		},
+		{
+			name:     "go",
+			language: "go",
+			files: map[string]string{
+				"go.mod":    "module example.com/secretmanager\n\ngo 1.23\n",
+				"client.go": "",
+			},
+			want: []string{"go.mod"},
+		},
+		{
+			name:     "cargo",
+			language: "rust",
+			files: map[string]string{
+				"Cargo.toml": "[package]\nname = \"gax\"\nreadme = \"README.md\"\nbuild = \"build.rs\"\n",
+				"README.md":  "",
+				"build.rs":   "",
+				"src/lib.rs": "",
+			},
+			want: []string{"Cargo.toml", "README.md", "build.rs"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, test.files)
+			files, err := List(dir, test.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := References(dir, test.language, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("References() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
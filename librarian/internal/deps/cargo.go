@@ -0,0 +1,176 @@
+package deps
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// parseCargoToml returns the crate name and the crates named in the
+// dependency tables of a Cargo.toml file. Renamed dependencies are recorded
+// by their package name.
+func parseCargoToml(content string) *manifest {
+	m := &manifest{}
+	var table, tableDep string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripTOMLComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[] ")
+			tableDep = ""
+			// [dependencies.foo] declares the dependency foo as a table.
+			if t, name, ok := cutDependencyTable(table); ok {
+				table, tableDep = t, name
+				m.requires = append(m.requires, name)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case table == "package" && key == "name":
+			m.name = strings.Trim(value, `"'`)
+		case tableDep != "" && key == "package":
+			// A renamed dependency: replace the key with the package name.
+			m.requires[len(m.requires)-1] = strings.Trim(value, `"'`)
+		case tableDep == "" && isDependencyTable(table):
+			name, _, _ := strings.Cut(key, ".") // foo.workspace = true
+			if p := cargoPackageRegex.FindStringSubmatch(value); p != nil {
+				name = p[1]
+			}
+			m.requires = append(m.requires, strings.Trim(name, `"'`))
+		}
+	}
+	return m
+}
+
+// cargoPackageRegex matches the package key of an inline dependency table.
+var cargoPackageRegex = regexp.MustCompile(`\bpackage\s*=\s*["']([^"']+)["']`)
+
+// isDependencyTable reports whether table is a Cargo dependency table, such
+// as dependencies, dev-dependencies, or target.'cfg(unix)'.dependencies.
+func isDependencyTable(table string) bool {
+	for _, t := range []string{"dependencies", "dev-dependencies", "build-dependencies"} {
+		if table == t || strings.HasSuffix(table, "."+t) {
+			return true
+		}
+	}
+	return false
+}
+
+// cutDependencyTable splits a table header such as dependencies.foo into the
+// dependency table and the dependency name.
+func cutDependencyTable(table string) (string, string, bool) {
+	i := strings.LastIndex(table, ".")
+	if i < 0 || !isDependencyTable(table[:i]) {
+		return "", "", false
+	}
+	return table[:i], strings.Trim(table[i+1:], `"'`), true
+}
+
+func stripTOMLComment(line string) string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// UpdateWorkspaceRequirement sets the version required of crate in the
+// [workspace.dependencies] table of the Cargo.toml file at path. It reports
+// whether the file was changed.
+func UpdateWorkspaceRequirement(path, crate, version string) (bool, error) {
+	return updateFile(path, func(content string) string {
+		return updateCargoRequire(content, crate, version, func(table string) bool {
+			return table == "workspace.dependencies"
+		})
+	})
+}
+
+// updateCargoRequire sets the version required of crate in the tables of a
+// Cargo.toml file accepted by inTable. It updates entries of the form
+// `crate = "1.2.0"` and `crate = { version = "1.2.0", path = "..." }`,
+// including renamed dependencies, and the version key of a
+// [dependencies.crate] table. Requirements inherited
+// from the workspace are left unchanged.
+func updateCargoRequire(content, crate, version string, inTable func(string) bool) string {
+	version = strings.TrimPrefix(version, "v")
+	lines := strings.Split(content, "\n")
+	var table string
+	var inCrateTable bool
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(stripTOMLComment(line))
+		if strings.HasPrefix(trimmed, "[") {
+			table = strings.Trim(trimmed, "[] ")
+			t, name, ok := cutDependencyTable(table)
+			inCrateTable = ok && name == crate && inTable(t)
+			continue
+		}
+		switch {
+		case inCrateTable:
+			lines[i] = tableRequirementRegex.ReplaceAllString(line, "${1}${2}${3}"+version+"${4}")
+		case inTable(table):
+			m := cargoEntryRegex.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := m[2]
+			if p := cargoPackageRegex.FindStringSubmatch(m[3]); p != nil {
+				name = p[1]
+			}
+			if name == crate {
+				lines[i] = m[1] + replaceRequirement(m[3], version)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	// cargoEntryRegex matches a dependency entry, capturing the key and
+	// the value.
+	cargoEntryRegex = regexp.MustCompile(`^(\s*["']?([A-Za-z0-9_-]+)["']?\s*=\s*)(.*)$`)
+
+	// quotedRequirementRegex matches a quoted version requirement at the
+	// start of a value, such as "1.2.0" or "^1.2".
+	quotedRequirementRegex = regexp.MustCompile(`^(["'])([=^~<>\s]*)[0-9][^"']*(["'])`)
+
+	// inlineRequirementRegex matches the version key of an inline table.
+	inlineRequirementRegex = regexp.MustCompile(`(\bversion\s*=\s*)(["'])([=^~<>\s]*)[0-9][^"']*(["'])`)
+
+	// tableRequirementRegex matches the version key of a dependency table.
+	tableRequirementRegex = regexp.MustCompile(`^(\s*version\s*=\s*)(["'])([=^~<>\s]*)[0-9][^"']*(["'])`)
+)
+
+// replaceRequirement replaces the version in a dependency value, keeping any
+// comparison operator.
+func replaceRequirement(value, version string) string {
+	if quotedRequirementRegex.MatchString(value) {
+		return quotedRequirementRegex.ReplaceAllString(value, "${1}${2}"+version+"${3}")
+	}
+	return inlineRequirementRegex.ReplaceAllString(value, "${1}${2}${3}"+version+"${4}")
+}
+
+// updateFile rewrites the file at path with update. It reports whether the
+// file was changed, and returns false if the file does not exist.
+func updateFile(path string, update func(string) string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	updated := update(string(data))
+	if updated == string(data) {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
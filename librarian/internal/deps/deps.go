@@ -0,0 +1,332 @@
+// Package deps computes dependencies between artifacts in a repository.
+//
+// Dependencies are read from each artifact's package manifest (go.mod,
+// Cargo.toml, or pubspec.yaml). An artifact depends on another artifact when
+// its manifest requires or replaces the other artifact's package.
+package deps
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Graph records the dependencies between artifacts.
+type Graph struct {
+	paths []string            // artifact paths, sorted
+	names map[string]string   // artifact path -> package name
+	deps  map[string][]string // artifact path -> paths of its dependencies
+}
+
+// Load builds the dependency graph of the artifacts at paths.
+func Load(paths []string) (*Graph, error) {
+	g := &Graph{
+		names: make(map[string]string),
+		deps:  make(map[string][]string),
+	}
+	g.paths = append(g.paths, paths...)
+	sort.Strings(g.paths)
+
+	requires := make(map[string][]string)
+	byName := make(map[string]string)
+	for _, path := range g.paths {
+		m, err := readManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest for %s: %w", path, err)
+		}
+		if m == nil {
+			continue
+		}
+		g.names[path] = m.name
+		requires[path] = m.requires
+		byName[m.name] = path
+	}
+
+	for _, path := range g.paths {
+		seen := make(map[string]bool)
+		for _, name := range requires[path] {
+			dep, ok := byName[name]
+			if !ok || dep == path || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			g.deps[path] = append(g.deps[path], dep)
+		}
+		sort.Strings(g.deps[path])
+	}
+	return g, nil
+}
+
+// Name returns the package name of the artifact at path, or an empty string
+// if the artifact has no recognized manifest.
+func (g *Graph) Name(path string) string {
+	return g.names[path]
+}
+
+// Dependencies returns the paths of the artifacts that the artifact at path
+// depends on.
+func (g *Graph) Dependencies(path string) []string {
+	return g.deps[path]
+}
+
+// Order returns the artifact paths in dependency order: every artifact appears
+// after the artifacts it depends on. Artifacts that are not ordered by a
+// dependency are sorted by path, so the order is stable. It returns an error if
+// the dependencies contain a cycle.
+func (g *Graph) Order() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	status := make(map[string]int)
+	var order []string
+	var visit func(path string, stack []string) error
+	visit = func(path string, stack []string) error {
+		switch status[path] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(stack, path), " -> "))
+		}
+		status[path] = visiting
+		for _, dep := range g.deps[path] {
+			if err := visit(dep, append(stack, path)); err != nil {
+				return err
+			}
+		}
+		status[path] = done
+		order = append(order, path)
+		return nil
+	}
+	for _, path := range g.paths {
+		if err := visit(path, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// manifest is the package name and requirements read from a package manifest.
+type manifest struct {
+	name     string
+	requires []string
+}
+
+// readManifest reads the package manifest in dir: go.mod, Cargo.toml, or
+// pubspec.yaml. It returns nil if dir has no recognized manifest.
+func readManifest(dir string) (*manifest, error) {
+	for _, name := range []string{"go.mod", "Cargo.toml", "pubspec.yaml"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		switch name {
+		case "go.mod":
+			return parseGoMod(string(data)), nil
+		case "Cargo.toml":
+			return parseCargoToml(string(data)), nil
+		default:
+			return parsePubspec(string(data))
+		}
+	}
+	return nil, nil
+}
+
+// parseGoMod returns the module path and the module paths named in require
+// and replace directives of a go.mod file.
+func parseGoMod(content string) *manifest {
+	m := &manifest{}
+	block := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		if block != "" {
+			if line == ")" {
+				block = ""
+				continue
+			}
+			m.addDirective(block, line)
+			continue
+		}
+		verb, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		if rest == "(" {
+			block = verb
+			continue
+		}
+		m.addDirective(verb, rest)
+	}
+	return m
+}
+
+func (m *manifest) addDirective(verb, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return
+	}
+	switch verb {
+	case "module":
+		m.name = strings.Trim(fields[0], `"`)
+	case "require", "replace":
+		m.requires = append(m.requires, strings.Trim(fields[0], `"`))
+	}
+}
+
+func stripComment(line string) string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// UpdateRequirement sets the required version of the named package in the
+// manifest in dir. It reports whether the manifest was changed.
+func UpdateRequirement(dir, name, version string) (bool, error) {
+	changed, err := updateFile(filepath.Join(dir, "go.mod"), func(content string) string {
+		return updateGoModRequire(content, name, version)
+	})
+	if err != nil || changed {
+		return changed, err
+	}
+	changed, err = updateFile(filepath.Join(dir, "Cargo.toml"), func(content string) string {
+		return updateCargoRequire(content, name, version, isDependencyTable)
+	})
+	if err != nil || changed {
+		return changed, err
+	}
+	return updateFile(filepath.Join(dir, "pubspec.yaml"), func(content string) string {
+		return updatePubspecRequire(content, name, version)
+	})
+}
+
+// updateGoModRequire rewrites the require directive for module in a go.mod
+// file to version. Replace directives are left unchanged.
+func updateGoModRequire(content, module, version string) string {
+	single := regexp.MustCompile(`^(\s*require\s+` + regexp.QuoteMeta(module) + `\s+)(\S+)(.*)$`)
+	inBlock := regexp.MustCompile(`^(\s*` + regexp.QuoteMeta(module) + `\s+)(\S+)(.*)$`)
+
+	lines := strings.Split(content, "\n")
+	inRequire := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inRequire && trimmed == ")":
+			inRequire = false
+		case inRequire:
+			lines[i] = inBlock.ReplaceAllString(line, "${1}"+version+"${3}")
+		case strings.HasPrefix(trimmed, "require") && strings.HasSuffix(trimmed, "("):
+			inRequire = true
+		default:
+			lines[i] = single.ReplaceAllString(line, "${1}"+version+"${3}")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RemoveLocalReplace removes the replace directives in dir/go.mod that
+// replace a module with the local directory target. dir and target are
+// relative to the same directory. It reports whether go.mod was changed; a
+// missing go.mod is not an error.
+func RemoveLocalReplace(dir, target string) (bool, error) {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false, err
+	}
+	return updateFile(filepath.Join(dir, "go.mod"), func(content string) string {
+		return removeGoModReplace(content, filepath.ToSlash(rel))
+	})
+}
+
+// HasLocalReplace reports whether dir/go.mod has a replace directive that
+// RemoveLocalReplace would remove. A missing go.mod is not an error.
+func HasLocalReplace(dir, target string) (bool, error) {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return removeGoModReplace(string(data), filepath.ToSlash(rel)) != string(data), nil
+}
+
+// removeGoModReplace removes the replace directives of a go.mod file whose
+// replacement is the local directory dir, a slash-separated path relative to
+// the go.mod file. A replace block left empty is removed as well.
+func removeGoModReplace(content, dir string) string {
+	var out []string
+	block := -1 // index in out of the open replace block, or -1
+	entries := 0
+	removed := false     // whether any directive was removed
+	justRemoved := false // whether the previous line was removed
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(stripComment(line))
+		switch {
+		case block >= 0 && trimmed == ")":
+			if entries == 0 {
+				out = out[:block]
+				block = -1
+				removed, justRemoved = true, true
+				continue
+			}
+			block = -1
+		case block >= 0:
+			if replacesWithDir(trimmed, dir) {
+				removed, justRemoved = true, true
+				continue
+			}
+			if trimmed != "" {
+				entries++
+			}
+		case trimmed == "replace (" || trimmed == "replace(":
+			block, entries = len(out), 0
+		case strings.HasPrefix(trimmed, "replace ") && replacesWithDir(strings.TrimPrefix(trimmed, "replace "), dir):
+			removed, justRemoved = true, true
+			continue
+		}
+		// Do not leave two blank lines where a directive was removed.
+		if justRemoved && line == "" && len(out) > 0 && out[len(out)-1] == "" {
+			continue
+		}
+		justRemoved = false
+		out = append(out, line)
+	}
+	for removed && len(out) > 1 && out[len(out)-1] == "" && out[len(out)-2] == "" {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, "\n")
+}
+
+// replacesWithDir reports whether the replace directive arguments args, such
+// as "example.com/storage => ../storage", replace a module with the local
+// directory dir.
+func replacesWithDir(args, dir string) bool {
+	_, replacement, ok := strings.Cut(args, "=>")
+	if !ok {
+		return false
+	}
+	fields := strings.Fields(replacement)
+	if len(fields) != 1 {
+		// A module path and version, not a directory.
+		return false
+	}
+	p := fields[0]
+	if !strings.HasPrefix(p, "./") && !strings.HasPrefix(p, "../") && !strings.HasPrefix(p, "/") {
+		return false
+	}
+	return path.Clean(p) == path.Clean(dir)
+}
@@ -0,0 +1,269 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeGoMod(t, filepath.Join(dir, "storage"), "module example.com/storage\n\nrequire example.com/auth v1.0.0\n")
+	writeGoMod(t, filepath.Join(dir, "auth"), "module example.com/auth\n")
+	writeGoMod(t, filepath.Join(dir, "bigquery"), `module example.com/bigquery
+
+require (
+	example.com/storage v1.2.0 // indirect
+	golang.org/x/net v0.1.0
+)
+
+replace example.com/auth => ../auth
+`)
+	if err := os.MkdirAll(filepath.Join(dir, "handwritten"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	g, err := Load([]string{path("storage"), path("handwritten"), path("bigquery"), path("auth")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := g.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{path("auth"), path("storage"), path("bigquery"), path("handwritten")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Order() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{path("auth"), path("storage")}, g.Dependencies(path("bigquery"))); diff != "" {
+		t.Errorf("Dependencies() mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := g.Name(path("storage")), "example.com/storage"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+}
+
+func TestOrderCycle(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	writeGoMod(t, a, "module example.com/a\n\nrequire example.com/b v1.0.0\n")
+	writeGoMod(t, b, "module example.com/b\n\nrequire example.com/a v1.0.0\n")
+
+	g, err := Load([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Order(); err == nil {
+		t.Error("Order() succeeded, want cycle error")
+	}
+}
+
+func TestUpdateGoModRequire(t *testing.T) {
+	content := `module example.com/bigquery
+
+require example.com/auth v1.0.0
+
+require (
+	example.com/storage v1.2.0 // indirect
+	example.com/storagex v1.2.0
+)
+
+replace example.com/storage => ../storage
+`
+	want := `module example.com/bigquery
+
+require example.com/auth v1.0.0
+
+require (
+	example.com/storage v1.3.0 // indirect
+	example.com/storagex v1.2.0
+)
+
+replace example.com/storage => ../storage
+`
+	got := updateGoModRequire(content, "example.com/storage", "v1.3.0")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	got = updateGoModRequire(content, "example.com/auth", "v2.0.0")
+	if want := "require example.com/auth v2.0.0\n"; !strings.Contains(got, want) {
+		t.Errorf("updateGoModRequire() = %q, want it to contain %q", got, want)
+	}
+}
+
+func writeGoMod(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrderCargoAndPubspec(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	writeFile(t, path("auth/Cargo.toml"), "[package]\nname = \"google-cloud-auth\"\nversion = \"0.22.0\"\n")
+	writeFile(t, path("gax/Cargo.toml"), `[package]
+name = "google-cloud-gax"
+
+[dependencies]
+auth = { version = "0.22", path = "../auth", package = "google-cloud-auth" }
+serde.workspace = true
+`)
+	writeFile(t, path("secretmanager/Cargo.toml"), `[package]
+name = "google-cloud-secretmanager-v1"
+
+[dependencies.google-cloud-gax]
+version = "1.0.0"
+path = "../gax"
+
+[dev-dependencies]
+google-cloud-auth = "0.22"
+`)
+	writeFile(t, path("dart_auth/pubspec.yaml"), "name: google_cloud_auth\nversion: 0.1.0\n")
+	writeFile(t, path("dart_storage/pubspec.yaml"), "name: google_cloud_storage\ndependencies:\n  google_cloud_auth: ^0.1.0\n  http: ^1.2.0\n")
+
+	g, err := Load([]string{path("secretmanager"), path("gax"), path("auth"), path("dart_storage"), path("dart_auth")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := g.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{path("auth"), path("dart_auth"), path("dart_storage"), path("gax"), path("secretmanager")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Order() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{path("auth"), path("gax")}, g.Dependencies(path("secretmanager"))); diff != "" {
+		t.Errorf("Dependencies() mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := g.Name(path("dart_storage")), "google_cloud_storage"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateCargoRequire(t *testing.T) {
+	content := `[package]
+name = "google-cloud-secretmanager-v1"
+version = "1.0.0"
+
+[dependencies]
+google-cloud-auth = { version = "0.22", path = "../auth" }
+google-cloud-authx = "0.22"
+auth = { version = "0.22", package = "google-cloud-auth" }
+gax = { workspace = true }
+
+[dependencies.google-cloud-gax]
+version = "=1.0.0"
+path = "../gax"
+
+[dev-dependencies]
+google-cloud-auth = "^0.22"
+`
+	got := updateCargoRequire(content, "google-cloud-auth", "v0.23.0", isDependencyTable)
+	got = updateCargoRequire(got, "google-cloud-gax", "1.1.0", isDependencyTable)
+	want := `[package]
+name = "google-cloud-secretmanager-v1"
+version = "1.0.0"
+
+[dependencies]
+google-cloud-auth = { version = "0.23.0", path = "../auth" }
+google-cloud-authx = "0.22"
+auth = { version = "0.23.0", package = "google-cloud-auth" }
+gax = { workspace = true }
+
+[dependencies.google-cloud-gax]
+version = "=1.1.0"
+path = "../gax"
+
+[dev-dependencies]
+google-cloud-auth = "^0.23.0"
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdatePubspecRequire(t *testing.T) {
+	content := `name: google_cloud_storage
+version: 0.1.0
+dependencies:
+  google_cloud_auth: ^0.1.0
+  google_cloud_authx: ^0.1.0
+  google_cloud_gax:
+    path: ../gax
+    version: '>=0.1.0 <0.2.0'
+dev_dependencies:
+  google_cloud_auth: 0.1.0 # pinned
+`
+	got := updatePubspecRequire(content, "google_cloud_auth", "0.2.0")
+	got = updatePubspecRequire(got, "google_cloud_gax", "v0.2.0")
+	want := `name: google_cloud_storage
+version: 0.1.0
+dependencies:
+  google_cloud_auth: ^0.2.0
+  google_cloud_authx: ^0.1.0
+  google_cloud_gax:
+    path: ../gax
+    version: '^0.2.0'
+dev_dependencies:
+  google_cloud_auth: 0.2.0 # pinned
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveGoModReplace(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "single",
+			content: "module example.com/bigquery\n\nrequire example.com/storage v1.2.0\n\nreplace example.com/storage => ../storage\n",
+			want:    "module example.com/bigquery\n\nrequire example.com/storage v1.2.0\n",
+		},
+		{
+			name:    "block",
+			content: "module example.com/bigquery\n\nreplace (\n\texample.com/auth => ../auth\n\texample.com/storage v1.2.0 => ./../storage // local\n)\n",
+			want:    "module example.com/bigquery\n\nreplace (\n\texample.com/auth => ../auth\n)\n",
+		},
+		{
+			name:    "empty block",
+			content: "module example.com/bigquery\n\nreplace (\n\texample.com/storage => ../storage\n)\n\nrequire example.com/auth v1.0.0\n",
+			want:    "module example.com/bigquery\n\nrequire example.com/auth v1.0.0\n",
+		},
+		{
+			name:    "other replacements",
+			content: "module example.com/bigquery\n\nreplace example.com/storage => example.com/storage v1.1.0\n\nreplace example.com/storagex => ../storagex\n",
+			want:    "module example.com/bigquery\n\nreplace example.com/storage => example.com/storage v1.1.0\n\nreplace example.com/storagex => ../storagex\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := removeGoModReplace(test.content, "../storage")
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
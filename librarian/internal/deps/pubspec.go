@@ -0,0 +1,100 @@
+package deps
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pubspecDependencySections are the top-level pubspec.yaml keys that list
+// dependencies.
+var pubspecDependencySections = []string{"dependencies", "dev_dependencies", "dependency_overrides"}
+
+// parsePubspec returns the package name and the packages named in the
+// dependency sections of a pubspec.yaml file.
+func parsePubspec(content string) (*manifest, error) {
+	var pubspec map[string]any
+	if err := yaml.Unmarshal([]byte(content), &pubspec); err != nil {
+		return nil, err
+	}
+	m := &manifest{}
+	m.name, _ = pubspec["name"].(string)
+	for _, section := range pubspecDependencySections {
+		deps, _ := pubspec[section].(map[string]any)
+		var names []string
+		for name := range deps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		m.requires = append(m.requires, names...)
+	}
+	return m, nil
+}
+
+// pubspecConstraintRegex matches a version constraint such as ^1.2.0 or
+// ">=1.2.0 <2.0.0", capturing the operator of the lower bound.
+var pubspecConstraintRegex = regexp.MustCompile(`^(["']?)([\^<>=~\s]*)[0-9][^"'#]*?(["']?)(\s*(#.*)?)$`)
+
+// updatePubspecRequire sets the version constraint of the named package in
+// the dependency sections of a pubspec.yaml file, keeping its operator.
+// Constraints written as `name: ^1.2.0` and as a version key under name are
+// updated.
+func updatePubspecRequire(content, name, version string) string {
+	version = strings.TrimPrefix(version, "v")
+	lines := strings.Split(content, "\n")
+	var inSection bool
+	nameIndent := -1 // indentation of the name key while inside its mapping
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == 0 {
+			key, _, _ := strings.Cut(trimmed, ":")
+			inSection = false
+			for _, s := range pubspecDependencySections {
+				inSection = inSection || key == s
+			}
+			nameIndent = -1
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if nameIndent >= 0 && indent <= nameIndent {
+			nameIndent = -1
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case nameIndent >= 0 && key == "version":
+			lines[i] = line[:len(line)-len(value)] + replaceConstraint(value, version)
+		case nameIndent < 0 && key == name && value == "":
+			nameIndent = indent
+		case nameIndent < 0 && key == name:
+			lines[i] = line[:len(line)-len(value)] + replaceConstraint(value, version)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func replaceConstraint(value, version string) string {
+	m := pubspecConstraintRegex.FindStringSubmatch(value)
+	if m == nil {
+		return value
+	}
+	op := strings.TrimSpace(m[2])
+	if strings.Contains(value, "<") && !strings.HasPrefix(op, "<") {
+		// A range such as ">=1.2.0 <2.0.0" is replaced by a caret
+		// constraint, which Dart treats as the same range.
+		op = "^"
+	}
+	return m[1] + op + version + m[3] + m[4]
+}
@@ -0,0 +1,132 @@
+// Package diff computes line-based differences between texts.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 3
+
+// Unified returns a unified diff of old and new, in the format of
+// `diff -u`, with oldName and newName as the file names in the header. It
+// returns an empty string if old and new are equal.
+func Unified(oldName, newName, old, new string) string {
+	if old == new {
+		return ""
+	}
+	a, b := splitLines(old), splitLines(new)
+	edits := lineEdits(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(edits); {
+		// Find the next change and extend the hunk while changes are
+		// within 2*contextLines lines of each other.
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				last = i
+			} else if i-last > 2*contextLines {
+				break
+			}
+		}
+		lo, hi := max(first-contextLines, start), min(last+contextLines+1, len(edits))
+		writeHunk(&sb, edits, lo, hi)
+		start = hi
+	}
+	return sb.String()
+}
+
+// edit is a line of a diff: kept (' '), deleted ('-'), or inserted ('+').
+type edit struct {
+	op         byte
+	line       string
+	oldN, newN int // line numbers, counting from 1, before this line
+}
+
+func writeHunk(sb *strings.Builder, edits []edit, lo, hi int) {
+	var oldLen, newLen int
+	for _, e := range edits[lo:hi] {
+		if e.op != '+' {
+			oldLen++
+		}
+		if e.op != '-' {
+			newLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(edits[lo].oldN, oldLen), hunkRange(edits[lo].newN, newLen))
+	for _, e := range edits[lo:hi] {
+		sb.WriteByte(e.op)
+		sb.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty
+// range starts at the line before it, as in `diff -u`.
+func hunkRange(n, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", n)
+	}
+	if length == 1 {
+		return fmt.Sprint(n + 1)
+	}
+	return fmt.Sprintf("%d,%d", n+1, length)
+}
+
+// splitLines splits s into lines, keeping their line endings.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineEdits returns the edits turning a into b, from a longest common
+// subsequence of their lines. State files are small, so the quadratic
+// algorithm is fast enough.
+func lineEdits(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		default:
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		}
+	}
+	return edits
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnified(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "change",
+			old:  "config:\n  keep:\n    - README.md\nlanguage:\n  go:\n    module: example.com/a\n",
+			new:  "config:\n  keep:\n    - README.md\n    - doc.go\nlanguage:\n  go:\n    module: example.com/a\n",
+			want: `--- a
++++ b
+@@ -1,6 +1,7 @@
+ config:
+   keep:
+     - README.md
++    - doc.go
+ language:
+   go:
+     module: example.com/a
+`,
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			want: `--- a
++++ b
+@@ -1,5 +1,5 @@
+ 1
+-2
++TWO
+ 3
+ 4
+ 5
+@@ -9,4 +9,3 @@
+ 9
+ 10
+ 11
+-12
+`,
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "release:\n  version: v1.0.0\n",
+			want: `--- a
++++ b
+@@ -0,0 +1,2 @@
++release:
++  version: v1.0.0
+`,
+		},
+		{
+			name: "no trailing newline",
+			old:  "a\nb",
+			new:  "a\nc\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := Unified("a", "b", test.old, test.new)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Unified() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
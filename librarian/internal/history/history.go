@@ -0,0 +1,152 @@
+// Package history records the edits made to artifact state files by
+// `librarian edit`, so that the last edit of an artifact can be undone.
+//
+// The history is a stream of YAML documents, one per edit, appended to as
+// edits are made. Undoing an edit restores the file's previous content and
+// removes the edit from the history, so repeated undos step further back.
+package history
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the location of the edit history, relative to the repository
+// root.
+const File = ".librarian/history"
+
+// Edit records a change to a file.
+type Edit struct {
+	// Path is the path of the edited file, relative to the repository
+	// root.
+	Path string    `yaml:"path"`
+	Time time.Time `yaml:"time"`
+
+	// Before is the content of the file before the edit. Exists is false
+	// if the edit created the file.
+	Before string `yaml:"before"`
+	Exists bool   `yaml:"exists"`
+
+	// After is the content of the file after the edit.
+	After string `yaml:"after"`
+}
+
+// Load reads the edit history, oldest edit first.
+func Load() ([]*Edit, error) {
+	data, err := os.ReadFile(File)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read edit history: %w", err)
+	}
+	var edits []*Edit
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var e Edit
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse edit history: %w", err)
+		}
+		edits = append(edits, &e)
+	}
+	return edits, nil
+}
+
+// Record appends e to the edit history.
+func Record(e *Edit) error {
+	if err := os.MkdirAll(filepath.Dir(File), 0755); err != nil {
+		return fmt.Errorf("failed to create .librarian directory: %w", err)
+	}
+	data, err := marshal([]*Edit{e})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write edit history: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write edit history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write edit history: %w", err)
+	}
+	return nil
+}
+
+// Undo reverts the last recorded edit of the file at path and removes it
+// from the history. It returns the reverted edit. It fails if the file has
+// changed since the edit, so that later changes are not lost.
+func Undo(path string) (*Edit, error) {
+	edits, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	path = filepath.Clean(path)
+	i := len(edits) - 1
+	for i >= 0 && edits[i].Path != path {
+		i--
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("no edits of %s to undo", path)
+	}
+	e := edits[i]
+
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if string(current) != e.After {
+		return nil, fmt.Errorf("%s has changed since it was edited at %s; not undoing the edit", path, e.Time.Format(time.RFC3339))
+	}
+	data, err := marshal(append(edits[:i:i], edits[i+1:]...))
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Exists {
+		err = os.WriteFile(path, []byte(e.Before), 0644)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to undo edit of %s: %w", path, err)
+	}
+	if err := os.WriteFile(File, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write edit history: %w", err)
+	}
+	return e, nil
+}
+
+// marshal encodes edits as a stream of YAML documents.
+func marshal(edits []*Edit) ([]byte, error) {
+	if len(edits) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, e := range edits {
+		if err := enc.Encode(e); err != nil {
+			return nil, fmt.Errorf("failed to marshal edit history: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal edit history: %w", err)
+	}
+	// Start with a document separator so that appended streams remain a
+	// valid stream.
+	return append([]byte("---\n"), buf.Bytes()...), nil
+}
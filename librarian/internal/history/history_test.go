@@ -0,0 +1,105 @@
+package history
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// edit writes content to path and records the edit.
+func edit(t *testing.T, path, content string) {
+	t.Helper()
+	before, err := os.ReadFile(path)
+	exists := err == nil
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := &Edit{Path: path, Time: time.Now(), Before: string(before), Exists: exists, After: content}
+	if err := Record(e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestUndo(t *testing.T) {
+	chdir(t, t.TempDir())
+	edit(t, "auth.yaml", "keep:\n  - README.md\n")
+	edit(t, "storage.yaml", "exclude:\n  - internal/\n")
+	edit(t, "auth.yaml", "keep:\n  - README.md\n  - doc.go\n")
+
+	edits, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 3 {
+		t.Fatalf("Load() returned %d edits, want 3", len(edits))
+	}
+
+	if _, err := Undo("auth.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, "auth.yaml"), "keep:\n  - README.md\n"; got != want {
+		t.Errorf("after first undo, auth.yaml = %q, want %q", got, want)
+	}
+
+	// The edit created the file, so undoing it removes the file.
+	if _, err := Undo("auth.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("auth.yaml"); !os.IsNotExist(err) {
+		t.Errorf("after second undo, auth.yaml exists (err = %v), want removed", err)
+	}
+	if _, err := Undo("auth.yaml"); err == nil || !strings.Contains(err.Error(), "no edits") {
+		t.Errorf("Undo() = %v, want no edits error", err)
+	}
+
+	edits, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].Path != "storage.yaml" {
+		t.Errorf("Load() = %v, want only the storage.yaml edit", edits)
+	}
+
+	if _, err := Undo("storage.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if edits, err = Load(); err != nil || len(edits) != 0 {
+		t.Errorf("Load() = %v, %v, want no edits", edits, err)
+	}
+}
+
+func TestUndoChangedFile(t *testing.T) {
+	chdir(t, t.TempDir())
+	edit(t, "auth.yaml", "keep:\n  - README.md\n")
+	if err := os.WriteFile("auth.yaml", []byte("keep: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Undo("auth.yaml"); err == nil || !strings.Contains(err.Error(), "has changed") {
+		t.Errorf("Undo() = %v, want changed file error", err)
+	}
+	if got, want := readFile(t, "auth.yaml"), "keep: []\n"; got != want {
+		t.Errorf("auth.yaml = %q, want it unchanged %q", got, want)
+	}
+}
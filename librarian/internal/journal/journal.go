@@ -0,0 +1,136 @@
+// Package journal records the progress of a multi-artifact release so that an
+// interrupted release can be resumed or rolled back.
+package journal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/julieqiu/exp/librarian/internal/state"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the location of the release journal, relative to the repository
+// root.
+const File = ".librarian/release-journal.yaml"
+
+// Step is a step in releasing a single artifact.
+type Step string
+
+const (
+	// StepTag is creating the local git tag.
+	StepTag Step = "tag"
+	// StepPush is pushing the tag to the remote.
+	StepPush Step = "push"
+	// StepRelease is creating the hosted release.
+	StepRelease Step = "release"
+	// StepSave is recording the release in the artifact's state file.
+	StepSave Step = "save"
+)
+
+// Journal records the artifacts in a release and the steps completed for
+// each of them.
+type Journal struct {
+	// Local is set if the release only creates local tags.
+	Local   bool     `yaml:"local,omitempty"`
+	Entries []*Entry `yaml:"entries"`
+}
+
+// Entry records the release of a single artifact.
+type Entry struct {
+	Path            string            `yaml:"path"`
+	PreviousVersion string            `yaml:"previous_version"`
+	Prepared        state.ReleaseInfo `yaml:"prepared"`
+	Steps           []Step            `yaml:"steps,omitempty"`
+}
+
+// Load reads the release journal. It returns nil if there is no journal,
+// meaning that no release is in progress.
+func Load() (*Journal, error) {
+	data, err := os.ReadFile(File)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read release journal: %w", err)
+	}
+	var j Journal
+	if err := yaml.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse release journal: %w", err)
+	}
+	return &j, nil
+}
+
+// Save writes the release journal.
+func (j *Journal) Save() error {
+	if err := os.MkdirAll(filepath.Dir(File), 0755); err != nil {
+		return fmt.Errorf("failed to create .librarian directory: %w", err)
+	}
+	data, err := yaml.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal release journal: %w", err)
+	}
+	if err := os.WriteFile(File, data, 0644); err != nil {
+		return fmt.Errorf("failed to write release journal: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the release journal, marking the release as finished.
+func Remove() error {
+	if err := os.Remove(File); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove release journal: %w", err)
+	}
+	return nil
+}
+
+// Entry returns the entry for the artifact at path, or nil if the artifact is
+// not part of the release.
+func (j *Journal) Entry(path string) *Entry {
+	for _, e := range j.Entries {
+		if e.Path == path {
+			return e
+		}
+	}
+	return nil
+}
+
+// Done reports whether step has been completed for the artifact at path.
+func (j *Journal) Done(path string, step Step) bool {
+	e := j.Entry(path)
+	return e != nil && slices.Contains(e.Steps, step)
+}
+
+// Record marks step as completed for the artifact at path and saves the
+// journal.
+func (j *Journal) Record(path string, step Step) error {
+	e := j.Entry(path)
+	if e == nil {
+		return fmt.Errorf("%s is not part of the release", path)
+	}
+	if !slices.Contains(e.Steps, step) {
+		e.Steps = append(e.Steps, step)
+	}
+	return j.Save()
+}
+
+// Unpublished returns the paths of the artifacts whose tags still have to be
+// pushed or whose hosted releases still have to be created. It returns nil
+// for a local release.
+func (j *Journal) Unpublished() []string {
+	if j.Local {
+		return nil
+	}
+	var paths []string
+	for _, e := range j.Entries {
+		if slices.Contains(e.Steps, StepSave) {
+			continue
+		}
+		if !slices.Contains(e.Steps, StepPush) || !slices.Contains(e.Steps, StepRelease) {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,108 @@
+package journal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestJournal(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	j, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j != nil {
+		t.Fatalf("Load() = %v, want nil when no journal exists", j)
+	}
+
+	j = &Journal{Entries: []*Entry{
+		{
+			Path:            "auth",
+			PreviousVersion: "v1.0.0",
+			Prepared:        state.ReleaseInfo{Version: "v1.1.0", Tag: "auth/v1.1.0", Commit: "abc123"},
+		},
+		{
+			Path:            "storage",
+			PreviousVersion: "v2.0.0",
+			Prepared:        state.ReleaseInfo{Version: "v2.0.1", Tag: "storage/v2.0.1", Commit: "abc123"},
+		},
+	}}
+	if err := j.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record("auth", StepTag); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record("auth", StepPush); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record("missing", StepTag); err == nil {
+		t.Error("Record() for unknown path succeeded, want error")
+	}
+
+	got, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(j, got); diff != "" {
+		t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+	}
+	for _, test := range []struct {
+		path string
+		step Step
+		want bool
+	}{
+		{"auth", StepTag, true},
+		{"auth", StepPush, true},
+		{"auth", StepRelease, false},
+		{"storage", StepTag, false},
+		{"missing", StepTag, false},
+	} {
+		if done := got.Done(test.path, test.step); done != test.want {
+			t.Errorf("Done(%q, %q) = %t, want %t", test.path, test.step, done, test.want)
+		}
+	}
+
+	if err := Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(File); !os.IsNotExist(err) {
+		t.Errorf("journal still exists after Remove(): %v", err)
+	}
+}
+
+func TestUnpublished(t *testing.T) {
+	entries := func() []*Entry {
+		return []*Entry{
+			{Path: "auth", Steps: []Step{StepTag, StepPush, StepRelease, StepSave}},
+			{Path: "pubsub", Steps: []Step{StepTag, StepPush, StepRelease}},
+			{Path: "spanner", Steps: []Step{StepTag, StepPush}},
+			{Path: "storage", Steps: []Step{StepTag}},
+			{Path: "vision"},
+		}
+	}
+	j := &Journal{Entries: entries()}
+	if diff := cmp.Diff([]string{"spanner", "storage", "vision"}, j.Unpublished()); diff != "" {
+		t.Errorf("Unpublished() mismatch (-want +got):\n%s", diff)
+	}
+	j = &Journal{Local: true, Entries: entries()}
+	if got := j.Unpublished(); got != nil {
+		t.Errorf("Unpublished() = %q for a local release, want nil", got)
+	}
+}
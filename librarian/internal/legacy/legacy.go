@@ -0,0 +1,141 @@
+// Package legacy reads the state and configuration files of the previous
+// version of librarian, which kept every library of a repository in a single
+// .librarian/state.yaml file.
+package legacy
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateFile is the location of the legacy state file, relative to the
+// repository root.
+const StateFile = ".librarian/state.yaml"
+
+// State is the legacy state file.
+type State struct {
+	Image     string     `yaml:"image"`
+	Libraries []*Library `yaml:"libraries"`
+
+	// Other holds fields that are not recognized.
+	Other map[string]any `yaml:",inline"`
+}
+
+// Library is a library in the legacy state file.
+type Library struct {
+	ID                  string   `yaml:"id"`
+	Version             string   `yaml:"version"`
+	LastGeneratedCommit string   `yaml:"last_generated_commit"`
+	APIs                []*API   `yaml:"apis"`
+	SourceRoots         []string `yaml:"source_roots"`          // Directories holding the library's code
+	PreserveRegex       []string `yaml:"preserve_regex"`        // Files not overwritten by generation
+	RemoveRegex         []string `yaml:"remove_regex"`          // Generated files, removed before generation
+	ReleaseExcludePaths []string `yaml:"release_exclude_paths"` // Paths not part of a release
+	TagFormat           string   `yaml:"tag_format"`            // Uses the {id} and {version} placeholders
+
+	// Other holds fields that are not recognized.
+	Other map[string]any `yaml:",inline"`
+}
+
+// API is an API of a library in the legacy state file.
+type API struct {
+	Path          string `yaml:"path"`
+	ServiceConfig string `yaml:"service_config"`
+
+	// Other holds fields that are not recognized.
+	Other map[string]any `yaml:",inline"`
+}
+
+// Load reads the legacy state file at path.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read legacy state: %w", err)
+	}
+	var s State
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Tag returns the tag of version of the library, from its tag format.
+func (l *Library) Tag(version string) string {
+	r := strings.NewReplacer("{id}", l.ID, "{version}", strings.TrimPrefix(version, "v"))
+	return r.Replace(l.TagFormat)
+}
+
+// Keys returns the sorted names of the fields in other.
+func Keys(other map[string]any) []string {
+	var keys []string
+	for k := range other {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsLegacyConfig reports whether data is a legacy .librarian/config.yaml
+// file, rather than one in the current format, which has a librarian
+// section.
+func IsLegacyConfig(data []byte) (bool, error) {
+	var fields map[string]any
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return false, err
+	}
+	_, ok := fields["librarian"]
+	return !ok, nil
+}
+
+// ConfigFields returns a description of each setting in a legacy
+// .librarian/config.yaml file, such as "global_files_allowlist" or
+// "libraries[auth].release_blocked". None of them have an equivalent in the
+// current format.
+func ConfigFields(data []byte) ([]string, error) {
+	var c struct {
+		Libraries []map[string]any `yaml:"libraries"`
+		Other     map[string]any   `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	fields := Keys(c.Other)
+	for _, lib := range c.Libraries {
+		for _, k := range Keys(lib) {
+			if k != "id" {
+				fields = append(fields, fmt.Sprintf("libraries[%v].%s", lib["id"], k))
+			}
+		}
+	}
+	return fields, nil
+}
+
+// Glob converts a legacy path regular expression, such as
+// `^secretmanager/apiv1/[^/]*_client\.go$`, into a slash-separated path
+// pattern of the kind used by keep, remove, and exclude lists, such as
+// `secretmanager/apiv1/*_client.go`. A regular expression matching a
+// directory and everything in it, such as `^secretmanager/apiv1/.*$` or
+// `secretmanager/apiv1/`, becomes the directory path. An unescaped "." is
+// taken to be a literal dot, as it almost always is in these expressions.
+// anchored reports whether the expression is anchored at the start of the
+// path. ok is false if the expression cannot be written as a pattern.
+func Glob(re string) (pattern string, anchored, ok bool) {
+	p, anchored := strings.CutPrefix(re, "^")
+	p = strings.TrimSuffix(p, "$")
+	p = strings.TrimSuffix(p, ".*")
+	p = strings.TrimSuffix(p, "/")
+	p = strings.ReplaceAll(p, "[^/]*", "\x00")
+	if p == "" || strings.ContainsAny(p, "*()|+?{}[]^$") {
+		return "", anchored, false
+	}
+	// Backslashes may only escape dots.
+	if strings.Contains(strings.ReplaceAll(p, `\.`, ""), `\`) {
+		return "", anchored, false
+	}
+	p = strings.ReplaceAll(p, `\.`, ".")
+	return strings.ReplaceAll(p, "\x00", "*"), anchored, true
+}
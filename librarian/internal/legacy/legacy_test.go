@@ -0,0 +1,103 @@
+package legacy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoad(t *testing.T) {
+	for _, test := range []struct {
+		language string
+		want     int
+	}{
+		{"go", 182},
+		{"python", 231},
+	} {
+		t.Run(test.language, func(t *testing.T) {
+			s, err := Load(filepath.Join("..", "..", "testdata", test.language, "state.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(s.Libraries) != test.want {
+				t.Errorf("Load() read %d libraries, want %d", len(s.Libraries), test.want)
+			}
+			if len(s.Other) != 0 {
+				t.Errorf("Load() found unknown fields %v", Keys(s.Other))
+			}
+		})
+	}
+}
+
+func TestTag(t *testing.T) {
+	l := &Library{ID: "google-cloud-dlp", TagFormat: "{id}-v{version}"}
+	if got, want := l.Tag("3.2.0"), "google-cloud-dlp-v3.2.0"; got != want {
+		t.Errorf("Tag() = %q, want %q", got, want)
+	}
+	l = &Library{ID: "accessapproval", TagFormat: "{id}/v{version}"}
+	if got, want := l.Tag("v1.8.8"), "accessapproval/v1.8.8"; got != want {
+		t.Errorf("Tag() = %q, want %q", got, want)
+	}
+}
+
+func TestConfigFields(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "go", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	isLegacy, err := IsLegacyConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isLegacy {
+		t.Errorf("IsLegacyConfig() = false, want true")
+	}
+	got, err := ConfigFields(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) < 2 || got[0] != "global_files_allowlist" || got[1] != "libraries[auth].release_blocked" {
+		t.Errorf("ConfigFields() = %v, want global_files_allowlist and release_blocked fields", got)
+	}
+
+	isLegacy, err = IsLegacyConfig([]byte("librarian:\n  version: v0.1.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isLegacy {
+		t.Errorf("IsLegacyConfig() = true for the current format, want false")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	for _, test := range []struct {
+		re       string
+		want     string
+		anchored bool
+		ok       bool
+	}{
+		{`^secretmanager/apiv1/[^/]*_client\.go$`, "secretmanager/apiv1/*_client.go", true, true},
+		{`^secretmanager/apiv1/secretmanagerpb/.*$`, "secretmanager/apiv1/secretmanagerpb", true, true},
+		{`^internal/generated/snippets/secretmanager/`, "internal/generated/snippets/secretmanager", true, true},
+		{`packages/google-cloud-dlp/`, "packages/google-cloud-dlp", false, true},
+		{`docs/CHANGELOG.md`, "docs/CHANGELOG.md", false, true},
+		{`tests/unit/.*.py`, "", false, false},
+		{`^packages/x/google/.*/.*_pb2\.(?:py|pyi)$`, "", true, false},
+		{`^a\d$`, "", true, false},
+		{`^$`, "", true, false},
+	} {
+		got, anchored, ok := Glob(test.re)
+		if got != test.want || anchored != test.anchored || ok != test.ok {
+			t.Errorf("Glob(%q) = %q, %v, %v; want %q, %v, %v", test.re, got, anchored, ok, test.want, test.anchored, test.ok)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	got := Keys(map[string]any{"b": 1, "a": 2})
+	if diff := cmp.Diff([]string{"a", "b"}, got); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+}
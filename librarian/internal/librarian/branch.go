@@ -0,0 +1,82 @@
+package librarian
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/release"
+	"github.com/julieqiu/exp/librarian/internal/state"
+	"github.com/urfave/cli/v3"
+)
+
+// branchCreateCommand cuts a maintenance branch for an artifact from the
+// release tag of a version. The branch is named after the first maintenance
+// branch pattern unless --name is set.
+func branchCreateCommand(ctx context.Context, cmd *cli.Command) error {
+	path := cmd.StringArg("path")
+	version := cmd.StringArg("version")
+	if path == "" || version == "" {
+		return fmt.Errorf("usage: librarian branch create <path> <version>")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	artifact, err := state.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load artifact at %s: %w", path, err)
+	}
+	if artifact.Release == nil {
+		return fmt.Errorf("artifact at %s is not configured for release", path)
+	}
+
+	v, err := release.ParseVersionScheme(version, versionScheme(artifact))
+	if err != nil {
+		return err
+	}
+	tag, err := artifactTag(cfg, path, artifact, version)
+	if err != nil {
+		return err
+	}
+	exists, err := release.TagExists(tag)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("tag %s does not exist; a branch can only be cut from a released version", tag)
+	}
+
+	name := cmd.String("name")
+	if name == "" {
+		name, err = release.BranchName(cfg, v)
+		if err != nil {
+			return err
+		}
+	}
+	if !release.IsMaintenanceBranch(cfg, name) {
+		return fmt.Errorf("branch %s does not match a maintenance branch pattern in release.branch_patterns", name)
+	}
+	series, err := release.ParseSeries(name)
+	if err != nil {
+		return err
+	}
+	if !series.Contains(v) {
+		return fmt.Errorf("version %s is not in the %s series of branch %s", version, series, name)
+	}
+	exists, err = release.BranchExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("branch %s already exists", name)
+	}
+
+	if err := release.CreateBranch(name, tag); err != nil {
+		return err
+	}
+	fmt.Printf("Created branch %s from %s\n", name, tag)
+	fmt.Printf("Check out %s and run `librarian prepare %s` to prepare a %s patch release.\n", name, path, series)
+	return nil
+}
@@ -0,0 +1,454 @@
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/container"
+	"github.com/julieqiu/exp/librarian/internal/release"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+// generatorInputDir is the directory in the repository containing
+// language-specific generator input, mounted at /input.
+const generatorInputDir = ".librarian/generator-input"
+
+// runGenerator runs the generator for the artifact at path with rt and
+// copies the generated code into the repository, honoring the artifact's keep
+// and remove lists. The generator writes its output relative to the
+// repository root, as it does for the source roots in the request.
+// googleapisPath is the googleapis checkout mounted at /source. Generator
+// output is written to log, or to the standard output and error streams if
+// log is nil.
+func runGenerator(ctx context.Context, rt container.Runtime, googleapisPath, path string, artifact *state.Artifact, log io.Writer) error {
+	outputDir, err := os.MkdirTemp("", "librarian-output-")
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	dirs := &container.GenerateDirs{
+		Source: googleapisPath,
+		Output: outputDir,
+	}
+	if info, err := os.Stat(generatorInputDir); err == nil && info.IsDir() {
+		dirs.Input, err = filepath.Abs(generatorInputDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	image := containerImage(artifact.Generate.Container)
+	if log == nil {
+		fmt.Printf("Running %s...\n", image)
+	} else {
+		fmt.Fprintf(log, "Running %s...\n", image)
+	}
+	if err := container.Generate(ctx, rt, image, newGenerateRequest(path, artifact), dirs, log); err != nil {
+		return err
+	}
+
+	// Keep and remove patterns are relative to the artifact directory, and
+	// match the same repository-relative paths as the regular expressions
+	// in the request.
+	var keep, remove []string
+	if artifact.Config != nil {
+		keep = repoPatterns(path, artifact.Config.Keep)
+		remove = repoPatterns(path, artifact.Config.Remove)
+	}
+	if err := copyGeneratedFiles(outputDir, ".", keep); err != nil {
+		return fmt.Errorf("failed to copy generated files: %w", err)
+	}
+	if err := removeFiles(".", path, remove); err != nil {
+		return fmt.Errorf("failed to remove files: %w", err)
+	}
+	return nil
+}
+
+// repoPatterns returns patterns, relative to the artifact directory dir, as
+// patterns relative to the repository root.
+func repoPatterns(dir string, patterns []string) []string {
+	var out []string
+	for _, p := range patterns {
+		out = append(out, filepath.ToSlash(filepath.Join(dir, p)))
+	}
+	return out
+}
+
+// generateArtifact regenerates the artifact at path and records the hash of
+// its inputs in the artifact state. Unless force is set, the generator is not
+// run and errUpToDate is returned if the inputs are unchanged since the
+// artifact was last generated; the artifact state is still saved, so that it
+// records the current generation pins.
+func generateArtifact(ctx context.Context, rt container.Runtime, googleapisPath, path string, artifact *state.Artifact, force bool, log io.Writer) error {
+	hash, err := inputHash(googleapisPath, artifact)
+	if err != nil {
+		return err
+	}
+	if !force && hash == artifact.Generate.InputHash {
+		if err := artifact.Save(path); err != nil {
+			return fmt.Errorf("failed to save artifact state: %w", err)
+		}
+		return errUpToDate
+	}
+	if err := runGenerator(ctx, rt, googleapisPath, path, artifact, log); err != nil {
+		return err
+	}
+	artifact.Generate.InputHash = hash
+	if err := artifact.Save(path); err != nil {
+		return fmt.Errorf("failed to save artifact state: %w", err)
+	}
+	return nil
+}
+
+// syncGenerateState records the generation pins from cfg in the artifact
+// state. digest is the digest of the generator image, as returned by
+// verifyContainerImage.
+func syncGenerateState(cfg *config.Config, artifact *state.Artifact, digest string) {
+	g := artifact.Generate
+	g.Librarian = cfg.Librarian.Version
+	if c := cfg.Generate.Container; c != nil {
+		g.Container.Image = c.Image
+		g.Container.Tag = c.Tag
+		g.Container.Digest = digest
+	}
+	if r := cfg.Generate.Googleapis; r != nil {
+		g.Googleapis.Repo = r.Repo
+		g.Googleapis.Ref = r.Ref
+	}
+	if r := cfg.Generate.Discovery; r != nil {
+		g.Discovery.Repo = r.Repo
+		g.Discovery.Ref = r.Ref
+	}
+}
+
+// generateResult is the outcome of generating a single artifact.
+type generateResult struct {
+	Path     string        `json:"path"`
+	OK       bool          `json:"ok"`
+	Skipped  bool          `json:"skipped,omitempty"` // inputs unchanged; not regenerated
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"duration_seconds"`
+	Log      string        `json:"log"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// generateReport is the machine-readable summary of generate --all.
+type generateReport struct {
+	Passed  int               `json:"passed"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Results []*generateResult `json:"results"`
+}
+
+// generateFunc generates the artifact at path, writing its output to log.
+type generateFunc func(ctx context.Context, path string, artifact *state.Artifact, log io.Writer) error
+
+// generateAll runs gen for each artifact at paths using up to jobs concurrent
+// workers. The output of each artifact is written to its own file in logDir.
+// Failures do not stop the remaining artifacts from being generated. Results
+// are returned in the order of paths.
+func generateAll(ctx context.Context, artifacts map[string]*state.Artifact, paths []string, jobs int, logDir string, gen generateFunc) []*generateResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]*generateResult, len(paths))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = generateOne(ctx, paths[i], artifacts[paths[i]], logDir, gen)
+				fmt.Printf("  - %s: %s (%s)\n", paths[i], results[i].status(), results[i].Duration.Round(time.Millisecond))
+			}
+		}()
+	}
+	for i := range paths {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return results
+}
+
+// generateOne runs gen for the artifact at path, logging to a file in logDir.
+func generateOne(ctx context.Context, path string, artifact *state.Artifact, logDir string, gen generateFunc) *generateResult {
+	r := &generateResult{
+		Path: path,
+		Log:  filepath.Join(logDir, logFileName(path)),
+	}
+	start := time.Now()
+	err := func() error {
+		f, err := os.Create(r.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create log file: %w", err)
+		}
+		defer f.Close()
+		err = gen(ctx, path, artifact, f)
+		switch {
+		case errors.Is(err, errUpToDate):
+			fmt.Fprintf(f, "Skipped: %v\n", err)
+		case err != nil:
+			fmt.Fprintf(f, "\nError: %v\n", err)
+		}
+		return err
+	}()
+	r.Duration = time.Since(start)
+	r.Seconds = r.Duration.Seconds()
+	switch {
+	case errors.Is(err, errUpToDate):
+		r.OK, r.Skipped = true, true
+	case err != nil:
+		r.Error = err.Error()
+	default:
+		r.OK = true
+	}
+	return r
+}
+
+// status returns the status of r shown in summaries.
+func (r *generateResult) status() string {
+	switch {
+	case r.Skipped:
+		return "skipped"
+	case r.OK:
+		return "ok"
+	}
+	return "FAILED"
+}
+
+// logFileName returns the name of the log file for the artifact at path.
+func logFileName(path string) string {
+	name := strings.ReplaceAll(filepath.ToSlash(filepath.Clean(path)), "/", "_")
+	if name == "." {
+		name = "root"
+	}
+	return name + ".log"
+}
+
+// newGenerateReport summarizes results.
+func newGenerateReport(results []*generateResult) *generateReport {
+	report := &generateReport{Results: results}
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			report.Skipped++
+		case r.OK:
+			report.Passed++
+		default:
+			report.Failed++
+		}
+	}
+	return report
+}
+
+// writeSummary writes a pass/fail table for the report.
+func (report *generateReport) writeSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tSTATUS\tDURATION\tLOG")
+	for _, r := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Path, r.status(), r.Duration.Round(time.Millisecond), r.Log)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d skipped, %d failed\n", report.Passed, report.Skipped, report.Failed)
+	return err
+}
+
+// save writes the report as JSON to path.
+func (report *generateReport) save(path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// requestVersion returns the version of the artifact passed to the generator:
+// its released version without the "v" prefix, or "" if it has not been
+// released.
+func requestVersion(artifact *state.Artifact) string {
+	if artifact.Release != nil && release.Released(artifact.Release.Version) {
+		return strings.TrimPrefix(artifact.Release.Version, "v")
+	}
+	return ""
+}
+
+// newGenerateRequest builds the generate request for the artifact at path.
+func newGenerateRequest(path string, artifact *state.Artifact) *container.GenerateRequest {
+	req := &container.GenerateRequest{
+		ID:          filepath.Base(path),
+		SourceRoots: []string{filepath.ToSlash(path)},
+	}
+	req.Version = requestVersion(artifact)
+	for _, api := range artifact.Generate.APIs {
+		req.APIs = append(req.APIs, container.API{
+			Path:          api.Path,
+			ServiceConfig: api.ServiceYaml,
+		})
+	}
+	if artifact.Config != nil {
+		for _, pattern := range artifact.Config.Keep {
+			req.PreserveRegex = append(req.PreserveRegex, globRegex(path, pattern))
+		}
+		for _, pattern := range artifact.Config.Remove {
+			req.RemoveRegex = append(req.RemoveRegex, globRegex(path, pattern))
+		}
+	}
+	return req
+}
+
+// containerImage returns the image reference recorded in the artifact state.
+func containerImage(c state.ContainerState) string {
+	image := c.Image
+	if c.Tag != "" {
+		image += ":" + c.Tag
+	}
+	if c.Digest != "" {
+		// The container engine refuses to run an image whose content does
+		// not match the digest.
+		image += "@" + c.Digest
+	}
+	return image
+}
+
+// globRegex converts a keep or remove pattern, relative to the artifact
+// directory dir, into a regular expression matching repository-relative paths.
+func globRegex(dir, pattern string) string {
+	p := regexp.QuoteMeta(filepath.ToSlash(filepath.Join(dir, pattern)))
+	p = strings.ReplaceAll(p, `\*`, `[^/]*`)
+	return "^" + p + "(/.*)?$"
+}
+
+// matchPath reports whether the slash-separated relative path rel matches
+// pattern. A pattern matches the path itself, anything below it when it names
+// a directory, or any path matched by filepath.Match.
+func matchPath(pattern, rel string) bool {
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+	if rel == pattern || strings.HasPrefix(rel, pattern+"/") {
+		return true
+	}
+	matched, err := filepath.Match(pattern, rel)
+	return err == nil && matched
+}
+
+// matchAny reports whether rel matches any of the patterns.
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if matchPath(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyGeneratedFiles copies every file in src to dst. Existing files matching a
+// keep pattern, relative to dst, are not overwritten, and artifact state files
+// are never overwritten.
+func copyGeneratedFiles(src, dst string, keep []string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.Name() == ".librarian.yaml" {
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		if matchAny(keep, rel) {
+			if _, err := os.Stat(target); err == nil {
+				return nil
+			}
+		}
+		return copyFile(path, target)
+	})
+}
+
+// removeFiles deletes the files and directories below sub in root matching
+// any of the remove patterns, which are relative to root. sub is relative to
+// root.
+func removeFiles(root, sub string, remove []string) error {
+	if len(remove) == 0 {
+		return nil
+	}
+	dir := filepath.Join(root, sub)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	var matches []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if matchAny(remove, filepath.ToSlash(rel)) {
+			matches = append(matches, path)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, path := range matches {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file at src to dst, creating parent directories as
+// needed.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
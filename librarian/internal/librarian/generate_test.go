@@ -0,0 +1,310 @@
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/container"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+func TestNewGenerateRequest(t *testing.T) {
+	artifact := &state.Artifact{
+		Generate: &state.GenerateState{
+			APIs: []state.API{
+				{Path: "google/cloud/secretmanager/v1", ServiceYaml: "secretmanager_v1.yaml"},
+			},
+		},
+		Release: &state.ReleaseState{Version: "v1.2.0"},
+		Config: &state.ConfigState{
+			Keep:   []string{"CHANGES.md"},
+			Remove: []string{"temp"},
+		},
+	}
+	got := newGenerateRequest("packages/secretmanager", artifact)
+	want := &container.GenerateRequest{
+		ID:      "secretmanager",
+		Version: "1.2.0",
+		APIs: []container.API{
+			{Path: "google/cloud/secretmanager/v1", ServiceConfig: "secretmanager_v1.yaml"},
+		},
+		SourceRoots:   []string{"packages/secretmanager"},
+		PreserveRegex: []string{`^packages/secretmanager/CHANGES\.md(/.*)?$`},
+		RemoveRegex:   []string{`^packages/secretmanager/temp(/.*)?$`},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("newGenerateRequest() mismatch (-want +got):\n%s", diff)
+	}
+	for _, re := range got.PreserveRegex {
+		if !regexp.MustCompile(re).MatchString("packages/secretmanager/CHANGES.md") {
+			t.Errorf("regex %q does not match CHANGES.md", re)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"README.md", "README.md", true},
+		{"docs", "docs/index.md", true},
+		{"docs/", "docs/index.md", true},
+		{"docs", "docsfile.md", false},
+		{"*.md", "CHANGES.md", true},
+		{"*.md", "docs/index.md", false},
+		{"apiv1/*.go", "apiv1/client.go", true},
+	} {
+		t.Run(test.pattern+"_"+test.rel, func(t *testing.T) {
+			if got := matchPath(test.pattern, test.rel); got != test.want {
+				t.Errorf("matchPath(%q, %q) = %v, want %v", test.pattern, test.rel, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCopyGeneratedFiles(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"client.go":       "generated",
+		"README.md":       "generated",
+		"docs/index.md":   "generated",
+		".librarian.yaml": "generated",
+	})
+	writeFiles(t, dst, map[string]string{
+		"README.md":       "handwritten",
+		".librarian.yaml": "state",
+	})
+
+	if err := copyGeneratedFiles(src, dst, []string{"README.md", "docs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"client.go":       "generated",
+		"README.md":       "handwritten",
+		"docs/index.md":   "generated",
+		".librarian.yaml": "state",
+	}
+	if diff := cmp.Diff(want, readFiles(t, dst)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRemoveFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"client.go":    "",
+		"temp.txt":     "",
+		"build/out.o":  "",
+		"keep/temp.go": "",
+	})
+
+	if err := removeFiles(dir, ".", []string{"temp.txt", "build"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"client.go":    "",
+		"keep/temp.go": "",
+	}
+	if diff := cmp.Diff(want, readFiles(t, dir)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunGenerator(t *testing.T) {
+	repo := t.TempDir()
+	chdir(t, repo)
+	writeFiles(t, repo, map[string]string{
+		"secretmanager/.librarian.yaml":                  "state",
+		"secretmanager/CHANGES.md":                       "handwritten",
+		"secretmanager/apiv1/stale.go":                   "stale",
+		"internal/generated/snippets/secretmanager/a.go": "old snippet",
+	})
+	artifact := &state.Artifact{
+		Generate: &state.GenerateState{
+			APIs: []state.API{{Path: "google/cloud/secretmanager/v1"}},
+		},
+		Config: &state.ConfigState{
+			Keep:   []string{"CHANGES.md"},
+			Remove: []string{"apiv1/stale.go"},
+		},
+	}
+
+	// The generator writes its output relative to the repository root, as
+	// documented in container/go/README.md.
+	var req container.GenerateRequest
+	rt := &container.Local{Handlers: map[string]container.Handler{
+		"generate": func(ctx context.Context, dirs map[string]string) error {
+			data, err := os.ReadFile(filepath.Join(dirs["librarian"], "generate-request.json"))
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &req); err != nil {
+				return err
+			}
+			writeFiles(t, dirs["output"], map[string]string{
+				"secretmanager/apiv1/client.go":                  "generated",
+				"secretmanager/CHANGES.md":                       "generated",
+				"secretmanager/.librarian.yaml":                  "generated",
+				"internal/generated/snippets/secretmanager/a.go": "new snippet",
+			})
+			return nil
+		},
+	}}
+	if err := runGenerator(context.Background(), rt, t.TempDir(), "secretmanager", artifact, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"secretmanager"}, req.SourceRoots); diff != "" {
+		t.Errorf("source roots mismatch (-want +got):\n%s", diff)
+	}
+	want := map[string]string{
+		"secretmanager/.librarian.yaml":                  "state",
+		"secretmanager/CHANGES.md":                       "handwritten",
+		"secretmanager/apiv1/client.go":                  "generated",
+		"internal/generated/snippets/secretmanager/a.go": "new snippet",
+	}
+	if diff := cmp.Diff(want, readFiles(t, repo)); diff != "" {
+		t.Errorf("repository mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestGenerateAll(t *testing.T) {
+	logDir := t.TempDir()
+	paths := []string{"packages/a", "packages/b", "packages/c", "packages/d"}
+	artifacts := make(map[string]*state.Artifact)
+	for _, p := range paths {
+		artifacts[p] = &state.Artifact{Generate: &state.GenerateState{}}
+	}
+
+	var running, maxRunning atomic.Int32
+	gen := func(ctx context.Context, path string, artifact *state.Artifact, log io.Writer) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintf(log, "generating %s\n", path)
+		switch path {
+		case "packages/b":
+			return errors.New("generator crashed")
+		case "packages/d":
+			return errUpToDate
+		}
+		return nil
+	}
+
+	results := generateAll(context.Background(), artifacts, paths, 2, logDir, gen)
+	if got := maxRunning.Load(); got > 2 {
+		t.Errorf("ran %d generators concurrently, want at most 2", got)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, fmt.Sprintf("%s %s error=%q", r.Path, r.status(), r.Error))
+	}
+	want := []string{
+		`packages/a ok error=""`,
+		`packages/b FAILED error="generator crashed"`,
+		`packages/c ok error=""`,
+		`packages/d skipped error=""`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("generateAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	log, err := os.ReadFile(filepath.Join(logDir, "packages_b.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "generating packages/b\n\nError: generator crashed\n"; string(log) != want {
+		t.Errorf("log = %q, want %q", log, want)
+	}
+
+	report := newGenerateReport(results)
+	if report.Passed != 2 || report.Skipped != 1 || report.Failed != 1 {
+		t.Errorf("report passed=%d skipped=%d failed=%d, want 2, 1, and 1", report.Passed, report.Skipped, report.Failed)
+	}
+	reportPath := filepath.Join(logDir, "report.json")
+	if err := report.save(reportPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded generateReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Results) != 4 || decoded.Results[1].Error != "generator crashed" {
+		t.Errorf("report.json results = %+v", decoded.Results)
+	}
+}
@@ -0,0 +1,68 @@
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/source"
+)
+
+// googleapisSource returns the path to the googleapis sources used for
+// generation.
+//
+// If localDir is set, it is used as-is; this is useful when developing
+// against a local googleapis checkout. Otherwise the tarball for the
+// configured ref is fetched from the source cache, downloading it if needed.
+// The tarball is verified against generate.googleapis.sha256. If no checksum
+// is configured, the checksum of the tarball is recorded in
+// .librarian/config.yaml if record is set, and is otherwise reported in an
+// error so that it can be checked and recorded by hand.
+func googleapisSource(ctx context.Context, cfg *config.Config, localDir string, record bool) (string, error) {
+	if localDir != "" {
+		abs, err := filepath.Abs(localDir)
+		if err != nil {
+			return "", err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("googleapis directory %s does not exist", localDir)
+		}
+		fmt.Printf("Using local googleapis at %s\n", abs)
+		return abs, nil
+	}
+
+	if cfg.Generate == nil || cfg.Generate.Googleapis == nil || cfg.Generate.Googleapis.Ref == "" {
+		return "", fmt.Errorf("googleapis not configured")
+	}
+	g := cfg.Generate.Googleapis
+	cacheDir, err := source.DefaultCacheDir()
+	if err != nil {
+		return "", err
+	}
+	cache := &source.Cache{Dir: cacheDir}
+	dir, sum, err := cache.Fetch(ctx, &source.Tarball{
+		Name:   "googleapis",
+		Commit: g.Ref,
+		URL:    cfg.GoogleapisURL(),
+		SHA256: g.SHA256,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch googleapis at %s: %w", g.Ref, err)
+	}
+
+	if g.SHA256 == "" {
+		if !record {
+			return "", fmt.Errorf("generate.googleapis.sha256 is not set; the googleapis tarball at %s has sha256 %s: "+
+				"rerun with --record-sha256 to record it, or run `librarian config set generate.googleapis.sha256 %s`", g.Ref, sum, sum)
+		}
+		g.SHA256 = sum
+		if err := cfg.Save(); err != nil {
+			return "", fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("Recorded googleapis sha256 %s in .librarian/config.yaml\n", sum)
+	}
+	fmt.Printf("Using googleapis at %s from %s\n", g.Ref, dir)
+	return dir, nil
+}
@@ -0,0 +1,64 @@
+package librarian
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/source"
+)
+
+func TestGoogleapisSourceChecksum(t *testing.T) {
+	chdir(t, t.TempDir())
+	cacheDir := t.TempDir()
+	t.Setenv(source.CacheEnv, cacheDir)
+
+	// Seed the cache so that no download is needed.
+	const ref = "c288189b43c016dd3cf1ec73ce3cadee8b732f07"
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := "googleapis"
+	if err := tw.WriteHeader(&tar.Header{Name: "googleapis-" + ref + "/README.md", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte(content))
+	tw.Close()
+	gz.Close()
+	writeFiles(t, filepath.Join(cacheDir, "googleapis"), map[string]string{ref + ".tar.gz": buf.String()})
+	h := sha256.Sum256(buf.Bytes())
+	sum := hex.EncodeToString(h[:])
+
+	cfg := &config.Config{Generate: &config.GenerateConfig{
+		Googleapis: &config.RepoConfig{Repo: "github.com/googleapis/googleapis", Ref: ref},
+	}}
+	_, err := googleapisSource(context.Background(), cfg, "", false)
+	if err == nil || !strings.Contains(err.Error(), sum) {
+		t.Fatalf("googleapisSource() error = %v, want an error reporting sha256 %s", err, sum)
+	}
+	if cfg.Generate.Googleapis.SHA256 != "" {
+		t.Errorf("googleapisSource() recorded sha256 %s without --record-sha256", cfg.Generate.Googleapis.SHA256)
+	}
+	if _, err := os.Stat(".librarian/config.yaml"); !os.IsNotExist(err) {
+		t.Errorf("googleapisSource() wrote .librarian/config.yaml without --record-sha256")
+	}
+
+	if _, err := googleapisSource(context.Background(), cfg, "", true); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Generate.Googleapis.SHA256; got != sum {
+		t.Errorf("recorded sha256 = %q, want %q", got, sum)
+	}
+}
@@ -0,0 +1,77 @@
+package librarian
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/container"
+	"github.com/julieqiu/exp/librarian/internal/registry"
+)
+
+// updateContainerImage pins c to the newest tag of its image and the digest
+// of that tag, as reported by the image's registry. It prints the old and new
+// values and reports whether c changed.
+func updateContainerImage(ctx context.Context, client *registry.Client, c *config.ContainerConfig) (bool, error) {
+	if c == nil || c.Image == "" {
+		return false, fmt.Errorf("generate.container.image is not set")
+	}
+	tag, digest, err := client.Latest(ctx, c.Image)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve latest generator image: %w", err)
+	}
+	if tag == c.Tag && digest == c.Digest {
+		fmt.Printf("Generator image is up to date (%s)\n", imageVersion(c.Tag, c.Digest))
+		return false, nil
+	}
+	fmt.Printf("Updating generator image %s\n", c.Image)
+	fmt.Printf("  from %s\n", imageVersion(c.Tag, c.Digest))
+	fmt.Printf("  to   %s\n", imageVersion(tag, digest))
+	c.Tag, c.Digest = tag, digest
+	return true, nil
+}
+
+// imageVersion formats an image tag and digest for display.
+func imageVersion(tag, digest string) string {
+	if tag == "" {
+		tag = "(no tag)"
+	}
+	if digest == "" {
+		return tag
+	}
+	return tag + "@" + digest
+}
+
+// verifyContainerImage returns the digest to run the generator image c with,
+// or an empty string to run it by tag.
+//
+// If c pins a digest, the registry is asked which digest the tag currently
+// refers to, and an error is returned if the tag has since been moved to a
+// different image, rather than generating with an image other than the one
+// recorded. Otherwise the registry is not contacted: the digest of the copy
+// of the image that the container engine has already pulled is used, if
+// there is one. The local runtime runs no image, so no digest is returned.
+func verifyContainerImage(ctx context.Context, client *registry.Client, c *config.ContainerConfig, rt container.Runtime) (string, error) {
+	if _, ok := rt.(*container.Local); ok || c == nil || c.Image == "" {
+		return "", nil
+	}
+	tag := c.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	if c.Digest == "" {
+		if e, ok := rt.(*container.Engine); ok {
+			return e.LocalDigest(ctx, c.Image+":"+tag), nil
+		}
+		return "", nil
+	}
+	digest, err := client.Digest(ctx, c.Image, tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify generator image: %w", err)
+	}
+	if digest != c.Digest {
+		return "", fmt.Errorf("generator image %s:%s has digest %s, but generate.container.digest is %s; "+
+			"run `librarian config update generate.container` to pin the current image", c.Image, tag, digest, c.Digest)
+	}
+	return digest, nil
+}
@@ -0,0 +1,116 @@
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/container"
+	"github.com/julieqiu/exp/librarian/internal/registry"
+)
+
+// serveImage starts a registry serving the image images/go-gen with the
+// given tags, whose manifests have the given digests. It returns the image
+// name.
+func serveImage(t *testing.T, tags []string, digests map[string]string) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/images/go-gen/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name": "images/go-gen", "tags": ["%s"]}`, strings.Join(tags, `", "`))
+	})
+	mux.HandleFunc("/v2/images/go-gen/manifests/", func(w http.ResponseWriter, r *http.Request) {
+		digest, ok := digests[strings.TrimPrefix(r.URL.Path, "/v2/images/go-gen/manifests/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://") + "/images/go-gen"
+}
+
+func TestUpdateContainerImage(t *testing.T) {
+	const digest = "sha256:4c5e0a1f9d3b"
+	image := serveImage(t, []string{"latest", "v1.1.0", "v1.2.0"}, map[string]string{"v1.2.0": digest})
+
+	c := &config.ContainerConfig{Image: image, Tag: "v1.1.0"}
+	changed, err := updateContainerImage(context.Background(), &registry.Client{}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config.ContainerConfig{Image: image, Tag: "v1.2.0", Digest: digest}
+	if !changed {
+		t.Error("updateContainerImage() = false, want true")
+	}
+	if diff := cmp.Diff(want, c); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	changed, err = updateContainerImage(context.Background(), &registry.Client{}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("updateContainerImage() = true for an up-to-date image, want false")
+	}
+}
+
+// fakeEngine writes a container engine that prints output for any command,
+// or fails if output is empty.
+func fakeEngine(t *testing.T, output string) *container.Engine {
+	t.Helper()
+	script := "#!/bin/sh\nexit 1\n"
+	if output != "" {
+		script = fmt.Sprintf("#!/bin/sh\necho %q\n", output)
+	}
+	program := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(program, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &container.Engine{Program: program}
+}
+
+func TestVerifyContainerImage(t *testing.T) {
+	const digest = "sha256:4c5e0a1f9d3b"
+	image := serveImage(t, nil, map[string]string{"v1.2.0": digest, "latest": digest})
+	engine := &container.Engine{Program: "docker"}
+	// Unpinned images must not contact the registry.
+	const unreachable = "unreachable.example.com/gen"
+	for _, test := range []struct {
+		name string
+		c    *config.ContainerConfig
+		rt   container.Runtime
+		want string
+	}{
+		{"pinned", &config.ContainerConfig{Image: image, Tag: "v1.2.0", Digest: digest}, engine, digest},
+		{"unpinned", &config.ContainerConfig{Image: unreachable, Tag: "v1.2.0"}, fakeEngine(t, unreachable+"@sha256:9b2f7e6d"), "sha256:9b2f7e6d"},
+		{"unpinned and not pulled", &config.ContainerConfig{Image: unreachable, Tag: "v1.2.0"}, fakeEngine(t, ""), ""},
+		{"no tag", &config.ContainerConfig{Image: unreachable}, fakeEngine(t, unreachable+"@sha256:9b2f7e6d"), "sha256:9b2f7e6d"},
+		{"local runtime", &config.ContainerConfig{Image: unreachable, Tag: "v1"}, &container.Local{Generator: "gen"}, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := verifyContainerImage(context.Background(), &registry.Client{}, test.c, test.rt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("verifyContainerImage() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	c := &config.ContainerConfig{Image: image, Tag: "v1.2.0", Digest: "sha256:9b2f7e6d"}
+	_, err := verifyContainerImage(context.Background(), &registry.Client{}, c, engine)
+	if err == nil || !strings.Contains(err.Error(), "generate.container.digest is sha256:9b2f7e6d") {
+		t.Errorf("verifyContainerImage() = %v, want digest mismatch error", err)
+	}
+}
@@ -0,0 +1,154 @@
+package librarian
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+// errUpToDate is returned when an artifact is not regenerated because its
+// inputs have not changed since it was last generated.
+var errUpToDate = errors.New("inputs unchanged since last generation")
+
+// inputHash returns a hash of the inputs used to generate artifact from the
+// googleapis checkout at googleapisPath. The inputs are the librarian
+// version, the container image and tag, the artifact version passed to the
+// generator, the artifact's keep and remove patterns, the files in the
+// generator input directory, and for each API, its generation options, the
+// proto files under its path and the protos they import from elsewhere in
+// googleapis, and its service config files.
+//
+// The hash depends only on file contents, so an artifact's hash does not
+// change when googleapis moves to a commit that does not touch its APIs.
+func inputHash(googleapisPath string, artifact *state.Artifact) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "librarian %s\n", artifact.Generate.Librarian)
+	fmt.Fprintf(h, "container %s\n", containerImage(artifact.Generate.Container))
+	fmt.Fprintf(h, "version %s\n", requestVersion(artifact))
+	if c := artifact.Config; c != nil {
+		fmt.Fprintf(h, "keep %s\n", strings.Join(c.Keep, ","))
+		fmt.Fprintf(h, "remove %s\n", strings.Join(c.Remove, ","))
+	}
+
+	// hashed records the protos that have been hashed, relative to
+	// googleapisPath, in the order they were hashed.
+	var hashed []string
+	seen := make(map[string]bool)
+	for _, api := range artifact.Generate.APIs {
+		fmt.Fprintf(h, "api %s\n", api.Path)
+		fmt.Fprintf(h, "transport %s\n", api.Transport)
+		fmt.Fprintf(h, "rest_numeric_enums %t\n", api.RestNumericEnums)
+		fmt.Fprintf(h, "opt_args %s\n", strings.Join(api.OptArgs, ","))
+
+		apiDir := filepath.Join(googleapisPath, api.Path)
+		err := filepath.WalkDir(apiDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != ".proto" {
+				return nil
+			}
+			rel, err := filepath.Rel(googleapisPath, path)
+			if err != nil {
+				return err
+			}
+			seen[filepath.ToSlash(rel)] = true
+			hashed = append(hashed, filepath.ToSlash(rel))
+			return hashFile(h, googleapisPath, path)
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to hash protos for %s: %w", api.Path, err)
+		}
+		for _, name := range []string{api.ServiceYaml, api.GrpcServiceConfig} {
+			if name == "" {
+				continue
+			}
+			if err := hashFile(h, googleapisPath, filepath.Join(apiDir, name)); err != nil {
+				return "", fmt.Errorf("failed to hash service config for %s: %w", api.Path, err)
+			}
+		}
+	}
+
+	// Hash the protos imported from outside the APIs' directories, and the
+	// protos they import in turn. Imports that are not in googleapis, such
+	// as the well-known types, are skipped.
+	for i := 0; i < len(hashed); i++ {
+		imports, err := protoImports(filepath.Join(googleapisPath, hashed[i]))
+		if err != nil {
+			return "", fmt.Errorf("failed to read imports of %s: %w", hashed[i], err)
+		}
+		for _, imp := range imports {
+			if seen[imp] {
+				continue
+			}
+			seen[imp] = true
+			path := filepath.Join(googleapisPath, filepath.FromSlash(imp))
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			hashed = append(hashed, imp)
+			if err := hashFile(h, googleapisPath, path); err != nil {
+				return "", fmt.Errorf("failed to hash imported proto %s: %w", imp, err)
+			}
+		}
+	}
+
+	if _, err := os.Stat(generatorInputDir); err == nil {
+		err := filepath.WalkDir(generatorInputDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return hashFile(h, ".", path)
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to hash generator input: %w", err)
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var protoImportRegex = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+
+// protoImports returns the files imported by the proto file at path, in the
+// order they are imported.
+func protoImports(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var imports []string
+	for _, m := range protoImportRegex.FindAllSubmatch(data, -1) {
+		imports = append(imports, string(m[1]))
+	}
+	return imports, nil
+}
+
+// hashFile writes the path of the file, relative to root, and the hash of
+// its contents to h.
+func hashFile(h hash.Hash, root, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fh := sha256.New()
+	if _, err := io.Copy(fh, f); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(h, "file %s %x\n", filepath.ToSlash(rel), fh.Sum(nil))
+	return nil
+}
@@ -0,0 +1,145 @@
+package librarian
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+func TestInputHash(t *testing.T) {
+	chdir(t, t.TempDir())
+	googleapis := t.TempDir()
+	writeFiles(t, googleapis, map[string]string{
+		"google/cloud/secretmanager/v1/service.proto":         "syntax = \"proto3\";\nimport \"google/api/client.proto\";\nimport \"google/protobuf/empty.proto\";\n",
+		"google/api/client.proto":                             "syntax = \"proto3\";\nimport public \"google/api/launch_stage.proto\";\n",
+		"google/api/launch_stage.proto":                       "syntax = \"proto3\";\n",
+		"google/api/http.proto":                               "syntax = \"proto3\";\n",
+		"google/cloud/secretmanager/v1/resources.proto":       "syntax = \"proto3\";\n",
+		"google/cloud/secretmanager/v1/secretmanager_v1.yaml": "type: google.api.Service\n",
+		"google/cloud/secretmanager/v1/BUILD.bazel":           "# build\n",
+		"google/cloud/storage/v2/storage.proto":               "syntax = \"proto3\";\n",
+	})
+	newArtifact := func() *state.Artifact {
+		return &state.Artifact{
+			Generate: &state.GenerateState{
+				Container: state.ContainerState{Image: "go-generator", Tag: "v1"},
+				APIs: []state.API{{
+					Path:        "google/cloud/secretmanager/v1",
+					ServiceYaml: "secretmanager_v1.yaml",
+					OptArgs:     []string{"release-level=ga"},
+				}},
+			},
+		}
+	}
+	hash := func(a *state.Artifact) string {
+		t.Helper()
+		h, err := inputHash(googleapis, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	base := hash(newArtifact())
+	if got := hash(newArtifact()); got != base {
+		t.Errorf("inputHash() is not deterministic: %s != %s", got, base)
+	}
+
+	// Changes outside the artifact's protos and service config do not
+	// change the hash.
+	writeFiles(t, googleapis, map[string]string{
+		"google/cloud/secretmanager/v1/BUILD.bazel": "# changed\n",
+		"google/cloud/storage/v2/storage.proto":     "syntax = \"proto3\";\n// changed\n",
+		"google/api/http.proto":                     "syntax = \"proto3\";\n// changed\n",
+	})
+	if got := hash(newArtifact()); got != base {
+		t.Errorf("inputHash() changed after unrelated files changed")
+	}
+
+	for _, test := range []struct {
+		name   string
+		change func(a *state.Artifact)
+	}{
+		{"librarian version", func(a *state.Artifact) { a.Generate.Librarian = "v0.6.0" }},
+		{"release version", func(a *state.Artifact) { a.Release = &state.ReleaseState{Version: "v1.2.0"} }},
+		{"container tag", func(a *state.Artifact) { a.Generate.Container.Tag = "v2" }},
+		{"container digest", func(a *state.Artifact) { a.Generate.Container.Digest = "sha256:4c5e0a1f9d3b" }},
+		{"opt_args", func(a *state.Artifact) { a.Generate.APIs[0].OptArgs = nil }},
+		{"keep", func(a *state.Artifact) { a.Config = &state.ConfigState{Keep: []string{"internal/version.go"}} }},
+		{"remove", func(a *state.Artifact) { a.Config = &state.ConfigState{Remove: []string{"*.tmp"}} }},
+		{"imported proto", func(a *state.Artifact) {
+			writeFiles(t, googleapis, map[string]string{
+				"google/api/client.proto": "syntax = \"proto3\";\nimport public \"google/api/launch_stage.proto\";\n// changed\n",
+			})
+		}},
+		{"transitively imported proto", func(a *state.Artifact) {
+			writeFiles(t, googleapis, map[string]string{
+				"google/api/launch_stage.proto": "syntax = \"proto3\";\n// changed\n",
+			})
+		}},
+		{"generator input", func(a *state.Artifact) {
+			writeFiles(t, generatorInputDir, map[string]string{"repo-config.yaml": "modules: []\n"})
+		}},
+		{"proto", func(a *state.Artifact) {
+			writeFiles(t, googleapis, map[string]string{
+				"google/cloud/secretmanager/v1/service.proto": "syntax = \"proto3\";\n// changed\n",
+			})
+		}},
+		{"service yaml", func(a *state.Artifact) {
+			writeFiles(t, googleapis, map[string]string{
+				"google/cloud/secretmanager/v1/secretmanager_v1.yaml": "type: google.api.Service\nname: changed\n",
+			})
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a := newArtifact()
+			before := hash(a)
+			test.change(a)
+			if got := hash(a); got == before {
+				t.Errorf("inputHash() unchanged after changing %s", test.name)
+			}
+		})
+	}
+}
+
+func TestGenerateArtifactUpToDate(t *testing.T) {
+	googleapis := t.TempDir()
+	writeFiles(t, googleapis, map[string]string{
+		"google/cloud/secretmanager/v1/service.proto": "syntax = \"proto3\";\n",
+	})
+	artifact := &state.Artifact{
+		Generate: &state.GenerateState{
+			Container: state.ContainerState{Image: "go-generator", Tag: "v1"},
+			APIs:      []state.API{{Path: "google/cloud/secretmanager/v1"}},
+		},
+	}
+	hash, err := inputHash(googleapis, artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact.Generate.InputHash = hash
+
+	// Pins synced from the config, such as the googleapis commit, do not
+	// change the hash but are still saved.
+	artifact.Generate.Googleapis.Ref = "c288189b43c016dd3cf1ec73ce3cadee8b732f07"
+	path := filepath.Join(t.TempDir(), "secretmanager")
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	err = generateArtifact(context.Background(), nil, googleapis, path, artifact, false, nil)
+	if !errors.Is(err, errUpToDate) {
+		t.Errorf("generateArtifact() = %v, want %v", err, errUpToDate)
+	}
+	saved, err := state.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(artifact, saved); diff != "" {
+		t.Errorf("saved state mismatch (-want +got):\n%s", diff)
+	}
+}
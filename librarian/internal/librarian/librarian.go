@@ -4,17 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/julieqiu/exp/librarian/internal/bazel"
 	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/container"
+	"github.com/julieqiu/exp/librarian/internal/deps"
+	"github.com/julieqiu/exp/librarian/internal/diff"
+	"github.com/julieqiu/exp/librarian/internal/history"
+	"github.com/julieqiu/exp/librarian/internal/registry"
 	"github.com/julieqiu/exp/librarian/internal/release"
 	"github.com/julieqiu/exp/librarian/internal/state"
+	"github.com/julieqiu/exp/librarian/internal/versionfile"
+	"github.com/julieqiu/exp/librarian/internal/yamlfile"
 	"github.com/urfave/cli/v3"
 )
 
@@ -30,6 +41,22 @@ func NewApp() *cli.Command {
 				Action:    initCommand,
 				Category:  "SETUP",
 			},
+			{
+				Name:  "migrate",
+				Usage: "Convert a legacy .librarian/state.yaml into .librarian.yaml files",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "language",
+						Usage: "Repository language (default inferred from the legacy image)",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Report what would be migrated without writing any files",
+					},
+				},
+				Action:   migrateCommand,
+				Category: "SETUP",
+			},
 			{
 				Name:  "config",
 				Usage: "Manage configuration",
@@ -65,8 +92,23 @@ func NewApp() *cli.Command {
 				Name:      "add",
 				Usage:     "Track a directory for management",
 				Arguments: []cli.Argument{&cli.StringArg{Name: "path"}},
-				Action:    addCommand,
-				Category:  "MANAGE",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "googleapis-dir",
+						Usage:   "Use a local googleapis checkout instead of downloading googleapis at the configured ref",
+						Sources: cli.EnvVars("LIBRARIAN_GOOGLEAPIS_DIR"),
+					},
+					&cli.BoolFlag{
+						Name:  "record-sha256",
+						Usage: "Record the checksum of the downloaded googleapis tarball in .librarian/config.yaml if none is configured",
+					},
+					&cli.StringSliceFlag{
+						Name:  "language",
+						Usage: "Language-specific metadata (format: LANG:KEY=VALUE, e.g., python:package=google-cloud-secret-manager)",
+					},
+				},
+				Action:   addCommand,
+				Category: "MANAGE",
 			},
 			{
 				Name:      "edit",
@@ -89,6 +131,14 @@ func NewApp() *cli.Command {
 						Name:  "language",
 						Usage: "Language-specific metadata (format: LANG:KEY=VALUE, e.g., go:module=github.com/user/repo)",
 					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the change to .librarian.yaml as a unified diff without saving it",
+					},
+					&cli.BoolFlag{
+						Name:  "undo",
+						Usage: "Revert the last edit of the artifact",
+					},
 				},
 				Action:   editCommand,
 				Category: "MANAGE",
@@ -97,8 +147,40 @@ func NewApp() *cli.Command {
 				Name:      "remove",
 				Usage:     "Stop tracking a directory",
 				Arguments: []cli.Argument{&cli.StringArg{Name: "path"}},
-				Action:    removeCommand,
-				Category:  "MANAGE",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "purge",
+						Usage: "Also delete generated files (except keep patterns), snippets, and go.mod replace directives pointing at the directory",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print what would be removed without changing any files",
+					},
+					&cli.BoolFlag{
+						Name:  "yes",
+						Usage: "Confirm that --purge may delete files",
+					},
+				},
+				Action:   removeCommand,
+				Category: "MANAGE",
+			},
+			{
+				Name:  "status",
+				Usage: "Summarize the state of all tracked artifacts",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the status as JSON",
+					},
+				},
+				Action:   statusCommand,
+				Category: "MANAGE",
+			},
+			{
+				Name:     "validate",
+				Usage:    "Check artifact state files for consistency",
+				Action:   validateCommand,
+				Category: "MANAGE",
 			},
 			{
 				Name:  "generate",
@@ -108,6 +190,38 @@ func NewApp() *cli.Command {
 						Name:  "all",
 						Usage: "Regenerate all artifacts",
 					},
+					&cli.IntFlag{
+						Name:  "jobs",
+						Usage: "Number of artifacts to generate concurrently with --all",
+						Value: runtime.NumCPU(),
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Regenerate artifacts with --all even if their inputs are unchanged",
+					},
+					&cli.StringFlag{
+						Name:  "log-dir",
+						Usage: "Directory for per-artifact logs and report.json with --all (default: a new temporary directory)",
+					},
+					&cli.StringFlag{
+						Name:    "googleapis-dir",
+						Usage:   "Use a local googleapis checkout instead of downloading googleapis at the configured ref",
+						Sources: cli.EnvVars("LIBRARIAN_GOOGLEAPIS_DIR"),
+					},
+					&cli.BoolFlag{
+						Name:  "record-sha256",
+						Usage: "Record the checksum of the downloaded googleapis tarball in .librarian/config.yaml if none is configured",
+					},
+					&cli.StringFlag{
+						Name:    "runtime",
+						Usage:   "How to run the generator: docker, podman, or local (default: generate.runtime in config.yaml, or docker)",
+						Sources: cli.EnvVars("LIBRARIAN_RUNTIME"),
+					},
+					&cli.StringFlag{
+						Name:    "generator",
+						Usage:   "Generator executable for the local runtime (default: generate.generator in config.yaml)",
+						Sources: cli.EnvVars("LIBRARIAN_GENERATOR"),
+					},
 				},
 				Arguments: []cli.Argument{&cli.StringArg{Name: "path"}},
 				Action:    generateCommand,
@@ -134,14 +248,49 @@ func NewApp() *cli.Command {
 				Action:    prepareCommand,
 				Category:  "MANAGE",
 			},
+			{
+				Name:  "branch",
+				Usage: "Manage maintenance branches",
+				Commands: []*cli.Command{
+					{
+						Name:      "create",
+						Usage:     "Cut a maintenance branch from the release tag of a version",
+						Arguments: []cli.Argument{&cli.StringArg{Name: "path"}, &cli.StringArg{Name: "version"}},
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "name",
+								Usage: "Branch name (default: derived from the maintenance branch pattern, e.g. release/v1.x)",
+							},
+						},
+						Action: branchCreateCommand,
+					},
+				},
+				Category: "MANAGE",
+			},
 			{
 				Name:  "release",
-				Usage: "Tag and publish a prepared release",
+				Usage: "Tag and publish a prepared release (GitHub releases require GITHUB_TOKEN; without it, tags are only pushed)",
 				Flags: []cli.Flag{
 					&cli.BoolFlag{
 						Name:  "all",
 						Usage: "Release all prepared artifacts",
 					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the planned tags and releases without making changes",
+					},
+					&cli.BoolFlag{
+						Name:  "local",
+						Usage: "Only create local tags; do not push tags or create GitHub releases",
+					},
+					&cli.BoolFlag{
+						Name:  "list-files",
+						Usage: "Print the files that ship in each release package, marking those left out by exclude patterns",
+					},
+					&cli.BoolFlag{
+						Name:  "rollback",
+						Usage: "Undo an incomplete release: delete its local tags and restore prepared state",
+					},
 				},
 				Arguments: []cli.Argument{&cli.StringArg{Name: "path"}},
 				Action:    releaseCommand,
@@ -153,9 +302,6 @@ func NewApp() *cli.Command {
 
 func initCommand(ctx context.Context, cmd *cli.Command) error {
 	language := cmd.StringArg("language")
-	if cmd.NArg() == 0 {
-		language = ""
-	}
 	supportedLanguages := []string{"go", "python", "rust", "dart", ""}
 	isSupported := false
 	for _, l := range supportedLanguages {
@@ -186,7 +332,7 @@ librarianVersion, err := getLibrarianVersion()
 		cfg.Librarian.Language = language
 		cfg.Generate = &config.GenerateConfig{
 			Container: &config.ContainerConfig{
-				Image: fmt.Sprintf("us-central1-docker.pkg.dev/cloud-sdk-librarian-prod/images-prod/%s-librarian-generator", language),
+				Image: defaultContainerImage(language),
 				Tag:   "latest",
 			},
 			Googleapis: &config.RepoConfig{
@@ -197,14 +343,13 @@ librarianVersion, err := getLibrarianVersion()
 				Repo: "github.com/googleapis/discovery-artifact-manager",
 				Ref:  "f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0",
 			},
-			Dir: "packages/",
+			Dir: defaultGenerateDir(language),
 		}
 	}
 
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
-	runYamlFmt(".librarian/config.yaml")
 
 	if language == "" {
 		fmt.Println("Initialized release-only librarian repository")
@@ -215,14 +360,32 @@ librarianVersion, err := getLibrarianVersion()
 	return nil
 }
 
+// defaultContainerImage returns the generator image for language.
+func defaultContainerImage(language string) string {
+	return fmt.Sprintf("us-central1-docker.pkg.dev/cloud-sdk-librarian-prod/images-prod/%s-librarian-generator", language)
+}
+
+// defaultGenerateDir returns the directory generated code is written to by
+// default, following each language's repository layout.
+func defaultGenerateDir(language string) string {
+	switch language {
+	case "rust":
+		return "src/generated/"
+	case "dart":
+		return "generated/"
+	default:
+		return "packages/"
+	}
+}
+
 func addCommand(ctx context.Context, cmd *cli.Command) error {
 	path := cmd.StringArg("path")
 	if path == "" {
 		return fmt.Errorf("path is required")
 	}
 
-	// Get all API paths (everything after the first argument)
-	apis := cmd.Args().Slice()[1:]
+	// Get all API paths (every argument after the path)
+	apis := cmd.Args().Slice()
 
 	fmt.Printf("Adding %s to librarian.\n", path)
 	if len(apis) > 0 {
@@ -238,7 +401,13 @@ func addCommand(ctx context.Context, cmd *cli.Command) error {
 	// Add release section if config has release enabled
 	if cfg.Release != nil && cfg.Release.TagFormat != "" {
 		artifact.Release = &state.ReleaseState{
-			Version: "null",
+			Version: release.NoVersion,
+		}
+	}
+
+	for _, flag := range cmd.StringSlice("language") {
+		if err := applyLanguageFlag(artifact, flag); err != nil {
+			return err
 		}
 	}
 
@@ -248,10 +417,9 @@ func addCommand(ctx context.Context, cmd *cli.Command) error {
 			return err
 		}
 
-		// Clone googleapis if needed
-		googleapisPath, err := cloneGoogleapis(cfg)
+		googleapisPath, err := googleapisSource(ctx, cfg, cmd.String("googleapis-dir"), cmd.Bool("record-sha256"))
 		if err != nil {
-			return fmt.Errorf("failed to clone googleapis: %w", err)
+			return err
 		}
 
 		// Parse BUILD.bazel for each API
@@ -269,13 +437,27 @@ func addCommand(ctx context.Context, cmd *cli.Command) error {
 				apiPath, apiConfig.Transport, apiConfig.GrpcServiceConfig)
 		}
 
+		// Python packages are named by the warehouse-package-name option
+		// of py_gapic_library unless set with --language
+		if cfg.Librarian.Language == "python" && (artifact.Language == nil || artifact.Language.Python == nil) {
+			for _, api := range apiConfigs {
+				if name := bazel.PythonPackage(&api); name != "" {
+					if err := applyLanguageFlag(artifact, "python:package="+name); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+
 		artifact.Generate = &state.GenerateState{
 			APIs:      apiConfigs,
 			Commit:    cfg.Generate.Googleapis.Ref,
 			Librarian: cfg.Librarian.Version,
 			Container: state.ContainerState{
-				Image: cfg.Generate.Container.Image,
-				Tag:   cfg.Generate.Container.Tag,
+				Image:  cfg.Generate.Container.Image,
+				Tag:    cfg.Generate.Container.Tag,
+				Digest: cfg.Generate.Container.Digest,
 			},
 			Googleapis: state.GoogleapisState{
 				Repo: cfg.Generate.Googleapis.Repo,
@@ -292,64 +474,17 @@ func addCommand(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
+	if err := setDefaultPackageName(cfg.Librarian.Language, path, artifact); err != nil {
+		return err
+	}
+
 	if err := artifact.Save(path); err != nil {
 		return err
 	}
-	runYamlFmt(filepath.Join(path, ".librarian.yaml"))
 	fmt.Printf("Created %s/.librarian.yaml\n", path)
 	return nil
 }
 
-// cloneGoogleapis clones the googleapis repository at the configured SHA.
-// Returns the path to the cloned repository.
-func cloneGoogleapis(cfg *config.Config) (string, error) {
-	if cfg.Generate == nil || cfg.Generate.Googleapis == nil {
-		return "", fmt.Errorf("googleapis not configured")
-	}
-
-	// Create a temp directory for googleapis
-	tmpDir := filepath.Join(os.TempDir(), "librarian-googleapis")
-	googleapisPath := filepath.Join(tmpDir, "googleapis")
-
-	// Check if already cloned at the right ref
-	if _, err := os.Stat(googleapisPath); err == nil {
-		// Already exists, check if it's at the right ref
-		cmd := exec.Command("git", "rev-parse", "HEAD")
-		cmd.Dir = googleapisPath
-		output, err := cmd.Output()
-		if err == nil && strings.TrimSpace(string(output)) == cfg.Generate.Googleapis.Ref {
-			fmt.Printf("Using cached googleapis at %s\n", cfg.Generate.Googleapis.Ref)
-			return googleapisPath, nil
-		}
-		// Wrong ref, remove and re-clone
-		os.RemoveAll(googleapisPath)
-	}
-
-	// Clone googleapis
-	os.MkdirAll(tmpDir, 0755)
-	fmt.Printf("Cloning googleapis at %s...\n", cfg.Generate.Googleapis.Ref)
-
-	// Clone with depth 1 for speed
-	repoURL := fmt.Sprintf("https://%s.git", cfg.Generate.Googleapis.Repo)
-	cmd := exec.Command("git", "clone", "--depth=1", "--branch", cfg.Generate.Googleapis.Ref, repoURL, googleapisPath)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		// Try without --branch if it's a SHA
-		cmd = exec.Command("git", "clone", repoURL, googleapisPath)
-		if output, err = cmd.CombinedOutput(); err != nil {
-			return "", fmt.Errorf("failed to clone googleapis: %w\n%s", err, output)
-		}
-		// Checkout the specific ref
-		cmd = exec.Command("git", "checkout", cfg.Generate.Googleapis.Ref)
-		cmd.Dir = googleapisPath
-		if output, err = cmd.CombinedOutput(); err != nil {
-			return "", fmt.Errorf("failed to checkout %s: %w\n%s", cfg.Generate.Googleapis.Ref, err, output)
-		}
-	}
-
-	fmt.Printf("Cloned googleapis to %s\n", googleapisPath)
-	return googleapisPath, nil
-}
-
 // parseAPIConfig parses a BUILD.bazel file and returns the API configuration.
 func parseAPIConfig(buildPath, apiPath, language string) (*state.API, error) {
 	// Use the bazel parser
@@ -383,14 +518,9 @@ func ensureGenerationConfig(cfg *config.Config) error {
 	}
 
 	// Initialize generator image if not set
-	if cfg.Generate.Container.Image == ""  {
-		if cfg.Librarian.Language == "python" {
-			cfg.Generate.Container.Image = "us-central1-docker.pkg.dev/cloud-sdk-librarian-prod/images-prod/python-librarian-generator"
-			cfg.Generate.Container.Tag = "latest"
-		} else if cfg.Librarian.Language == "go" {
-			cfg.Generate.Container.Image = "us-central1-docker.pkg.dev/cloud-sdk-librarian-prod/images-prod/go-librarian-generator"
-			cfg.Generate.Container.Tag = "latest"
-		}
+	if cfg.Generate.Container.Image == "" {
+		cfg.Generate.Container.Image = defaultContainerImage(cfg.Librarian.Language)
+		cfg.Generate.Container.Tag = "latest"
 		updated = true
 	}
 
@@ -428,7 +558,6 @@ func ensureGenerationConfig(cfg *config.Config) error {
 		if err := cfg.Save(); err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
 		}
-		runYamlFmt(".librarian/config.yaml")
 		fmt.Println("Initialized generation configuration")
 	}
 
@@ -451,39 +580,17 @@ func generateCommand(ctx context.Context, cmd *cli.Command) error {
 	if err := ensureGenerationConfig(cfg); err != nil {
 		return err
 	}
+	rt, err := newRuntime(cfg, cmd.String("runtime"), cmd.String("generator"))
+	if err != nil {
+		return err
+	}
+	digest, err := verifyContainerImage(ctx, &registry.Client{}, cfg.Generate.Container, rt)
+	if err != nil {
+		return err
+	}
 
 	if all {
-		// Regenerate all artifacts
-		artifacts, err := state.LoadAll()
-		if err != nil {
-			return fmt.Errorf("failed to load artifacts: %w", err)
-		}
-
-		fmt.Printf("Regenerating all %d artifacts...\n", len(artifacts))
-		for path, artifact := range artifacts {
-			if artifact.Generate == nil {
-				continue
-			}
-			fmt.Printf("  - Regenerating %s\n", path)
-
-			// Sync artifact state with current config
-			artifact.Generate.Librarian = cfg.Librarian.Version
-			artifact.Generate.Container.Image = cfg.Generate.Container.Image
-			artifact.Generate.Container.Tag = cfg.Generate.Container.Tag
-			artifact.Generate.Googleapis.Repo = cfg.Generate.Googleapis.Repo
-			artifact.Generate.Googleapis.Ref = cfg.Generate.Googleapis.Ref
-			artifact.Generate.Discovery.Repo = cfg.Generate.Discovery.Repo
-			artifact.Generate.Discovery.Ref = cfg.Generate.Discovery.Ref
-
-			if err := artifact.Save(path); err != nil {
-				return fmt.Errorf("failed to save artifact state: %w", err)
-			}
-			runYamlFmt(filepath.Join(path, ".librarian.yaml"))
-
-			// TODO: Run generator for each artifact
-		}
-		fmt.Println("Generation complete")
-		return nil
+		return generateAllCommand(ctx, cfg, rt, digest, cmd.String("googleapis-dir"), cmd.Bool("record-sha256"), int(cmd.Int("jobs")), cmd.String("log-dir"), cmd.Bool("force"))
 	}
 
 	if path == "" {
@@ -499,25 +606,84 @@ func generateCommand(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("artifact at %s is not configured for generation", path)
 	}
 
-	// Regenerating existing artifact - sync state with current config
+	// Regenerating existing artifact - sync state with current config. The
+	// state is saved only once generation succeeds.
 	fmt.Printf("Regenerating artifact at %s...\n", path)
+	syncGenerateState(cfg, artifact, digest)
 
-	artifact.Generate.Librarian = cfg.Librarian.Version
-	artifact.Generate.Container.Image = cfg.Generate.Container.Image
-	artifact.Generate.Container.Tag = cfg.Generate.Container.Tag
-	artifact.Generate.Googleapis.Repo = cfg.Generate.Googleapis.Repo
-	artifact.Generate.Googleapis.Ref = cfg.Generate.Googleapis.Ref
-	artifact.Generate.Discovery.Repo = cfg.Generate.Discovery.Repo
-	artifact.Generate.Discovery.Ref = cfg.Generate.Discovery.Ref
+	googleapisPath, err := googleapisSource(ctx, cfg, cmd.String("googleapis-dir"), cmd.Bool("record-sha256"))
+	if err != nil {
+		return err
+	}
+	fmt.Println("Running generator...")
+	if err := generateArtifact(ctx, rt, googleapisPath, path, artifact, true, nil); err != nil {
+		return fmt.Errorf("failed to generate %s: %w", path, err)
+	}
+	fmt.Println("Generation complete")
+	return nil
+}
 
-	// Save artifact state
-	if err := artifact.Save(path); err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
+// generateAllCommand regenerates every artifact with a generate section with
+// rt using jobs concurrent workers, using the googleapis sources in
+// googleapisDir if set. recordSHA256 is passed to googleapisSource. digest is
+// the verified generator image digest. Each artifact's output is logged to a
+// file in logDir, or in a new temporary directory if logDir is empty. A
+// summary table is printed and a JSON report is written to
+// logDir/report.json. Artifacts whose inputs are unchanged since they were
+// last generated are skipped unless force is set.
+func generateAllCommand(ctx context.Context, cfg *config.Config, rt container.Runtime, digest, googleapisDir string, recordSHA256 bool, jobs int, logDir string, force bool) error {
+	artifacts, err := state.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load artifacts: %w", err)
 	}
-	runYamlFmt(filepath.Join(path, ".librarian.yaml"))
+	var paths []string
+	for path, artifact := range artifacts {
+		if artifact.Generate != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
 
-	fmt.Println("Running generator...")
-	// TODO: Actually run the generator container
+	// Sync artifact state with current config. Each artifact's state is
+	// saved only once it is generated successfully.
+	for _, path := range paths {
+		syncGenerateState(cfg, artifacts[path], digest)
+	}
+
+	if logDir == "" {
+		logDir, err = os.MkdirTemp("", "librarian-generate-")
+		if err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+	} else if err := os.MkdirAll(logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	// Every artifact shares one googleapis source tree
+	googleapisPath, err := googleapisSource(ctx, cfg, googleapisDir, recordSHA256)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Regenerating all %d artifacts with %d workers...\n", len(paths), jobs)
+	results := generateAll(ctx, artifacts, paths, jobs, logDir, func(ctx context.Context, path string, artifact *state.Artifact, log io.Writer) error {
+		return generateArtifact(ctx, rt, googleapisPath, path, artifact, force, log)
+	})
+
+	report := newGenerateReport(results)
+	fmt.Println()
+	if err := report.writeSummary(os.Stdout); err != nil {
+		return err
+	}
+	reportPath := filepath.Join(logDir, "report.json")
+	if err := report.save(reportPath); err != nil {
+		return err
+	}
+	fmt.Printf("Report written to %s\n", reportPath)
+
+	if report.Failed > 0 {
+		return fmt.Errorf("generation failed for %d of %d artifacts", report.Failed, len(results))
+	}
 	fmt.Println("Generation complete")
 	return nil
 }
@@ -547,31 +713,49 @@ func configSetCommand(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("key and value are required")
 	}
 
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if err := cfg.Set(key, value); err != nil {
+	if err := config.Edit(key, value); err != nil {
 		return err
 	}
 
-	if err := cfg.Save(); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
-	}
-	runYamlFmt(".librarian/config.yaml")
-
 	fmt.Printf("Set %s = %s\n", key, value)
 	return nil
 }
 
+// configUpdateKeys are the keys accepted by config update.
+var configUpdateKeys = []string{"all", "generate.container", "generate.googleapis", "generate.discovery"}
+
+// configUpdateAliases maps the keys that config update accepted before they
+// were renamed to match the config.yaml keys.
+var configUpdateAliases = map[string]string{
+	"generator.image":      "generate.container",
+	"generator.googleapis": "generate.googleapis",
+	"generator.discovery":  "generate.discovery",
+}
+
+// configUpdateKey returns the config update key for key, resolving the old
+// generator.* names.
+func configUpdateKey(key string) (string, error) {
+	if alias, ok := configUpdateAliases[key]; ok {
+		fmt.Printf("Warning: %s is deprecated; use %s\n", key, alias)
+		key = alias
+	}
+	if key != "" && !slices.Contains(configUpdateKeys, key) {
+		return "", fmt.Errorf("unknown key %q for config update (want one of %s)", key, strings.Join(configUpdateKeys[1:], ", "))
+	}
+	return key, nil
+}
+
 func configUpdateCommand(ctx context.Context, cmd *cli.Command) error {
 	key := cmd.StringArg("key")
 	all := cmd.Bool("all")
 
-	if cmd.NArg() == 0 && !all {
+	if key == "" && !all {
 		return fmt.Errorf("key or --all is required")
 	}
+	key, err := configUpdateKey(key)
+	if err != nil {
+		return err
+	}
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -582,9 +766,9 @@ func configUpdateCommand(ctx context.Context, cmd *cli.Command) error {
 	var updated bool
 
 	updateAll := all || key == "all"
-	updateGeneratorImage := updateAll || key == "generator.image"
-	updateGoogleapis := updateAll || key == "generator.googleapis"
-	updateDiscovery := updateAll || key == "generator.discovery"
+	updateGeneratorImage := updateAll || key == "generate.container"
+	updateGoogleapis := updateAll || key == "generate.googleapis"
+	updateDiscovery := updateAll || key == "generate.discovery"
 
 	// Update librarian version
 	fmt.Printf("Current librarian version: %s\n", cfg.Librarian.Version)
@@ -610,6 +794,9 @@ librarianVersion, err := getLibrarianVersion()
 		if googleapisSHA != cfg.Generate.Googleapis.Ref {
 			fmt.Printf("Updating googleapis to %s\n", googleapisSHA[:7])
 			cfg.Generate.Googleapis.Ref = googleapisSHA
+			// The checksum must be recorded again for the new commit,
+			// with --record-sha256 or config set
+			cfg.Generate.Googleapis.SHA256 = ""
 			updated = true
 		} else {
 			fmt.Println("Googleapis is up to date")
@@ -625,23 +812,26 @@ librarianVersion, err := getLibrarianVersion()
 		if discoverySHA != cfg.Generate.Discovery.Ref {
 			fmt.Printf("Updating discovery to %s\n", discoverySHA[:7])
 			cfg.Generate.Discovery.Ref = discoverySHA
+			// The checksum is recorded again on the next download
+			cfg.Generate.Discovery.SHA256 = ""
 			updated = true
 		} else {
 			fmt.Println("Discovery is up to date")
 		}
 	}
 
-	if cfg.Librarian.Language != "" && updateGeneratorImage {
-		// Dummy update for generator image
-		fmt.Println("Generator image updated to latest.")
-		updated = true
+	if cfg.Librarian.Language != "" && cfg.Generate != nil && updateGeneratorImage {
+		changed, err := updateContainerImage(ctx, &registry.Client{}, cfg.Generate.Container)
+		if err != nil {
+			return err
+		}
+		updated = updated || changed
 	}
 
 	if updated {
 		if err := cfg.Save(); err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
 		}
-		runYamlFmt(".librarian/config.yaml")
 		fmt.Println("Configuration updated successfully")
 	} else {
 		fmt.Println("All versions are up to date")
@@ -657,9 +847,35 @@ func removeCommand(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("path is required")
 	}
 
+	purge := cmd.Bool("purge")
+	dryRun := cmd.Bool("dry-run")
+	if purge && !dryRun && !cmd.Bool("yes") {
+		return fmt.Errorf("--purge deletes files in %s; review them with --dry-run, then rerun with --yes", path)
+	}
+	if purge || dryRun {
+		artifact, err := state.Load(path)
+		if err != nil {
+			return fmt.Errorf("failed to load artifact at %s: %w", path, err)
+		}
+		if purge {
+			if err := purgeArtifact(path, artifact, dryRun); err != nil {
+				return err
+			}
+		}
+	}
+	if dryRun {
+		fmt.Printf("[dry run] Would remove %s\n", state.File(path))
+		fmt.Println("Dry run complete. No changes were made.")
+		return nil
+	}
+
 	if err := state.Remove(path); err != nil {
 		return err
 	}
+	if purge {
+		// Remove the artifact directory if nothing was kept in it.
+		os.Remove(path)
+	}
 
 	fmt.Printf("Removed artifact at %s\n", path)
 	return nil
@@ -675,6 +891,14 @@ func editCommand(ctx context.Context, cmd *cli.Command) error {
 	remove := cmd.StringSlice("remove")
 	exclude := cmd.StringSlice("exclude")
 	languageFlags := cmd.StringSlice("language")
+	dryRun := cmd.Bool("dry-run")
+
+	if cmd.Bool("undo") {
+		if len(keep)+len(remove)+len(exclude)+len(languageFlags) > 0 || dryRun {
+			return fmt.Errorf("--undo cannot be combined with other flags")
+		}
+		return undoEdit(path)
+	}
 
 	// Load existing artifact
 	artifact, err := state.Load(path)
@@ -682,8 +906,9 @@ func editCommand(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("failed to load artifact at %s: %w", path, err)
 	}
 
-	// Initialize config if needed
-	if artifact.Config == nil {
+	// Initialize config only if a config field is set, so that an
+	// empty config section is not added.
+	if artifact.Config == nil && len(keep)+len(remove)+len(exclude) > 0 {
 		artifact.Config = &state.ConfigState{}
 	}
 
@@ -707,67 +932,10 @@ func editCommand(ctx context.Context, cmd *cli.Command) error {
 
 	// Update language-specific fields if flags were provided
 	for _, flag := range languageFlags {
-		lang, key, value, err := parseLanguageFlag(flag)
-		if err != nil {
+		if err := applyLanguageFlag(artifact, flag); err != nil {
 			return err
 		}
-
-		if artifact.Language == nil {
-			artifact.Language = &state.LanguageState{}
-		}
-
-		switch lang {
-		case "go":
-			if artifact.Language.Go == nil {
-				artifact.Language.Go = &state.GoLanguage{}
-			}
-			switch key {
-			case "module":
-				artifact.Language.Go.Module = value
-				updated = true
-				fmt.Printf("Set Go module: %s\n", value)
-			default:
-				return fmt.Errorf("unknown Go property: %s (expected 'module')", key)
-			}
-		case "python":
-			if artifact.Language.Python == nil {
-				artifact.Language.Python = &state.PythonLanguage{}
-			}
-			switch key {
-			case "package":
-				artifact.Language.Python.Package = value
-				updated = true
-				fmt.Printf("Set Python package: %s\n", value)
-			default:
-				return fmt.Errorf("unknown Python property: %s (expected 'package')", key)
-			}
-		case "rust":
-			if artifact.Language.Rust == nil {
-				artifact.Language.Rust = &state.RustLanguage{}
-			}
-			switch key {
-			case "crate":
-				artifact.Language.Rust.Crate = value
-				updated = true
-				fmt.Printf("Set Rust crate: %s\n", value)
-			default:
-				return fmt.Errorf("unknown Rust property: %s (expected 'crate')", key)
-			}
-		case "dart":
-			if artifact.Language.Dart == nil {
-				artifact.Language.Dart = &state.DartLanguage{}
-			}
-			switch key {
-			case "package":
-				artifact.Language.Dart.Package = value
-				updated = true
-				fmt.Printf("Set Dart package: %s\n", value)
-			default:
-				return fmt.Errorf("unknown Dart property: %s (expected 'package')", key)
-			}
-		default:
-			return fmt.Errorf("unknown language: %s (expected go, python, rust, or dart)", lang)
-		}
+		updated = true
 	}
 
 	if !updated {
@@ -812,13 +980,71 @@ func editCommand(ctx context.Context, cmd *cli.Command) error {
 		return nil
 	}
 
-	// Save updated artifact
+	if dryRun {
+		before, after, err := stateChange(path, artifact)
+		if err != nil {
+			return err
+		}
+		file := filepath.ToSlash(state.File(path))
+		fmt.Print(diff.Unified("a/"+file, "b/"+file, before, after))
+		return nil
+	}
+	if err := saveEdit(path, artifact); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated configuration for %s\n", path)
+	return nil
+}
+
+// stateChange returns the content of the state file of the artifact at path
+// before and after saving artifact.
+func stateChange(path string, artifact *state.Artifact) (before, after string, err error) {
+	data, err := os.ReadFile(state.File(path))
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", err
+	}
+	updated, err := yamlfile.Marshal(data, artifact)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal artifact state: %w", err)
+	}
+	return string(data), string(updated), nil
+}
+
+// saveEdit saves artifact and records the change in the edit history, so
+// that it can be undone with `librarian edit --undo`.
+func saveEdit(path string, artifact *state.Artifact) error {
+	file := state.File(path)
+	_, statErr := os.Stat(file)
+	before, after, err := stateChange(path, artifact)
+	if err != nil {
+		return err
+	}
+	if before == after {
+		return nil
+	}
 	if err := artifact.Save(path); err != nil {
 		return fmt.Errorf("failed to save artifact state: %w", err)
 	}
-	runYamlFmt(filepath.Join(path, ".librarian.yaml"))
+	return history.Record(&history.Edit{
+		Path:   filepath.Clean(file),
+		Time:   time.Now().UTC().Truncate(time.Second),
+		Before: before,
+		Exists: statErr == nil,
+		After:  after,
+	})
+}
 
-	fmt.Printf("Updated configuration for %s\n", path)
+// undoEdit reverts the last edit of the artifact at path and prints the
+// reverted change.
+func undoEdit(path string) error {
+	e, err := history.Undo(state.File(path))
+	if err != nil {
+		return err
+	}
+	file := filepath.ToSlash(e.Path)
+	fmt.Printf("Reverted edit of %s made at %s\n", path, e.Time.Local().Format(time.DateTime))
+	fmt.Print(diff.Unified("a/"+file, "b/"+file, e.After, e.Before))
 	return nil
 }
 
@@ -828,7 +1054,7 @@ func prepareCommand(ctx context.Context, cmd *cli.Command) error {
 	prerelease := cmd.String("prerelease")
 	promote := cmd.Bool("promote")
 
-	if !all && cmd.NArg() == 0 {
+	if !all && path == "" {
 		return fmt.Errorf("either --all flag or path is required")
 	}
 
@@ -838,27 +1064,20 @@ func prepareCommand(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	artifacts, err := state.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load artifacts: %w", err)
+	}
+	var graph *deps.Graph
+	var paths []string
 	if all {
-		artifacts, err := state.LoadAll()
+		graph, paths, err = artifactOrder(artifacts)
 		if err != nil {
-			return fmt.Errorf("failed to load artifacts: %w", err)
+			return err
 		}
-
 		fmt.Printf("Preparing all %d artifacts for release...\n", len(artifacts))
-		for path, artifact := range artifacts {
-			if artifact.Release == nil {
-				continue
-			}
-			fmt.Printf("  - Preparing %s\n", path)
-			if err := prepareRelease(cfg, artifact, prerelease, promote); err != nil {
-				return fmt.Errorf("failed to prepare release for %s: %w", path, err)
-			}
-			if err := artifact.Save(path); err != nil {
-				return fmt.Errorf("failed to save artifact state for %s: %w", path, err)
-			}
-			runYamlFmt(filepath.Join(path, ".librarian.yaml"))
-		}
 	} else {
+		path = filepath.Clean(path)
 		artifact, err := state.Load(path)
 		if err != nil {
 			return fmt.Errorf("failed to load artifact at %s: %w", path, err)
@@ -866,31 +1085,134 @@ func prepareCommand(ctx context.Context, cmd *cli.Command) error {
 		if artifact.Release == nil {
 			return fmt.Errorf("artifact at %s is not configured for release", path)
 		}
+		artifacts[path] = artifact
+		var dirs []string
+		for p := range artifacts {
+			dirs = append(dirs, p)
+		}
+		graph, err = deps.Load(dirs)
+		if err != nil {
+			return fmt.Errorf("failed to load artifact dependencies: %w", err)
+		}
+		paths = []string{path}
 		fmt.Printf("Preparing artifact at %s for release...\n", path)
-		if err := prepareRelease(cfg, artifact, prerelease, promote); err != nil {
+	}
+	if err := checkTagCollisions(cfg, artifacts, paths); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		artifact := artifacts[path]
+		if artifact.Release == nil {
+			continue
+		}
+		if all {
+			fmt.Printf("  - Preparing %s\n", path)
+		}
+		depUpdates, err := dependencyUpdates(graph, artifacts, path)
+		if err != nil {
+			return err
+		}
+		prepared, err := prepareRelease(cfg, path, artifact, prerelease, promote, depUpdates)
+		if err != nil {
 			return fmt.Errorf("failed to prepare release for %s: %w", path, err)
 		}
+		if !prepared {
+			if all {
+				fmt.Printf("    No releasable changes, skipping\n")
+			} else {
+				fmt.Printf("No releasable changes for %s\n", path)
+			}
+			continue
+		}
 		if err := artifact.Save(path); err != nil {
 			return fmt.Errorf("failed to save artifact state for %s: %w", path, err)
 		}
-		runYamlFmt(filepath.Join(path, ".librarian.yaml"))
 	}
 
 	fmt.Println("Prepare complete")
 	return nil
 }
 
-func prepareRelease(cfg *config.Config, artifact *state.Artifact, prereleaseFlag string, promote bool) error {
+// dependencyUpdates updates the requirements of the artifact at path on
+// other artifacts to their latest released versions, and returns the
+// dependency updates for its release notes: the requirements it changed, and
+// the dependencies with a prepared release that has not been published yet.
+// Requirements are never set to a prepared version, since it has no tag until
+// it is released; within the repository, replace directives point at the
+// dependencies' directories instead.
+func dependencyUpdates(graph *deps.Graph, artifacts map[string]*state.Artifact, path string) ([]string, error) {
+	var updates []string
+	for _, dep := range graph.Dependencies(path) {
+		a, ok := artifacts[dep]
+		if !ok || a.Release == nil {
+			continue
+		}
+		name := graph.Name(dep)
+		if a.Release.Version != "" {
+			changed, err := deps.UpdateRequirement(path, name, a.Release.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update %s requirement for %s: %w", name, path, err)
+			}
+			if changed {
+				fmt.Printf("    Updated %s to %s\n", name, a.Release.Version)
+				updates = append(updates, fmt.Sprintf("update %s to %s", name, a.Release.Version))
+			}
+		}
+		if a.Release.Prepared != nil {
+			fmt.Printf("    Depends on %s %s, prepared for release\n", name, a.Release.Prepared.Version)
+			updates = append(updates, fmt.Sprintf("%s %s is released alongside this version", name, a.Release.Prepared.Version))
+		}
+	}
+	return updates, nil
+}
+
+// artifactOrder returns the dependency graph of artifacts and their paths in
+// dependency order, so that every artifact comes after the artifacts it
+// depends on.
+func artifactOrder(artifacts map[string]*state.Artifact) (*deps.Graph, []string, error) {
+	var paths []string
+	for path := range artifacts {
+		paths = append(paths, path)
+	}
+	graph, err := deps.Load(paths)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load artifact dependencies: %w", err)
+	}
+	order, err := graph.Order()
+	if err != nil {
+		return nil, nil, err
+	}
+	return graph, order, nil
+}
+
+// prepareRelease records the next release of the artifact at path in
+// artifact.Release.Prepared. The version increment is derived from the
+// conventional commits touching path since the last release, and from
+// depUpdates, which describe dependency requirements updated by this
+// prepare. It reports whether a release was prepared; artifacts without
+// releasable changes are left unchanged.
+func prepareRelease(cfg *config.Config, path string, artifact *state.Artifact, prereleaseFlag string, promote bool, depUpdates []string) (bool, error) {
 	// Get current branch and commit
 	branch, err := release.GetCurrentBranch()
 	if err != nil {
-		return err
+		return false, err
 	}
 	commit, err := release.GetCurrentCommit()
 	if err != nil {
-		return err
+		return false, err
 	}
 
+	// On a maintenance branch, releases continue the branch's version
+	// series rather than the artifact's main version.
+	maintenance := release.IsMaintenanceBranch(cfg, branch)
+	if maintenance {
+		if err := startMaintenanceBranch(cfg, path, artifact, branch); err != nil {
+			return false, err
+		}
+	}
+	currentVersion, history := releaseLine(artifact.Release, branch)
+
 	// Determine prerelease suffix
 	var prereleaseSuffix string
 	if promote {
@@ -903,129 +1225,374 @@ func prepareRelease(cfg *config.Config, artifact *state.Artifact, prereleaseFlag
 		// Auto-detect from branch patterns
 		detected, err := release.DetectPrerelease(cfg)
 		if err != nil {
-			return err
+			return false, err
 		}
 		prereleaseSuffix = detected
 	}
 
+	// Collect the changes since the previous release. When promoting, the
+	// notes cover every change since the last stable release.
+	sinceTag := lastReleaseTag(*history, promote)
+	if sinceTag == "" && maintenance {
+		// No release has been made from the branch yet; it starts at the
+		// latest release of its series.
+		sinceTag, err = artifactTag(cfg, path, artifact, *currentVersion)
+		if err != nil {
+			return false, err
+		}
+	}
+	commits, err := release.CommitsSince(sinceTag, path)
+	if err != nil {
+		return false, err
+	}
+
 	// Calculate next version
+	scheme := versionScheme(artifact)
 	var nextVersion string
 	if promote {
 		// Remove prerelease suffix from current version
-		nextVersion = release.RemovePrerelease(artifact.Release.Version)
+		current, err := release.ParseVersionScheme(*currentVersion, scheme)
+		if err != nil {
+			return false, err
+		}
+		nextVersion = current.Stable().String()
 	} else {
+		bump := release.DetermineBump(commits)
+		if bump == release.BumpNone && len(depUpdates) > 0 {
+			bump = release.BumpPatch
+		}
+		if maintenance && bump != release.BumpNone {
+			// Maintenance branches only release patches of their series.
+			bump = release.BumpPatch
+		}
+		firstRelease := !release.Released(*currentVersion)
+		if bump == release.BumpNone && !(firstRelease && len(commits) > 0) {
+			return false, nil
+		}
+
 		// Increment version with prerelease suffix
-		nextVersion, err = release.IncrementVersion(artifact.Release.Version, prereleaseSuffix)
+		nextVersion, err = release.IncrementVersion(*currentVersion, scheme, prereleaseSuffix, bump)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
 
+	tag, err := artifactTag(cfg, path, artifact, nextVersion)
+	if err != nil {
+		return false, err
+	}
+
+	notes := release.Notes(commits)
+	if len(depUpdates) > 0 {
+		notes = strings.TrimSpace(notes + "\n\n" + release.DependencyNotes(depUpdates))
+	}
+	if err := release.UpdateChangelog(path, nextVersion, notes, time.Now()); err != nil {
+		return false, err
+	}
+	changed, err := versionfile.Update(path, artifactLanguage(cfg, artifact), nextVersion)
+	if err != nil {
+		return false, err
+	}
+	for _, file := range changed {
+		fmt.Printf("    Updated version in %s\n", file)
+	}
+
 	// Update prepared release info
 	artifact.Release.Prepared = &state.ReleaseInfo{
 		Version: nextVersion,
-		Tag:     nextVersion,
+		Tag:     tag,
 		Commit:  commit,
 		Branch:  branch,
+		Notes:   notes,
 	}
 
-	return nil
+	return true, nil
 }
 
-func Atoi(s string) (int, error) {
-	i := 0
-	for _, r := range s {
-		if r < '0' || r > '9' {
-			return 0, fmt.Errorf("invalid digit: %c", r)
-		}
-		i = i*10 + int(r-'0')
-	}
-	return i, nil
+// artifactTag returns the git tag for version of the artifact at path, using
+// the repository's release.tag_format.
+func artifactTag(cfg *config.Config, path string, artifact *state.Artifact, version string) (string, error) {
+	var format string
+	if cfg.Release != nil {
+		format = cfg.Release.TagFormat
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	return release.FormatTag(format, release.TagFields{
+		ID:      filepath.Base(path),
+		Name:    artifactName(artifact),
+		Path:    path,
+		Version: version,
+	})
 }
 
-func releaseCommand(ctx context.Context, cmd *cli.Command) error {
-	all := cmd.Bool("all")
-	path := cmd.StringArg("path")
-
-	if !all && cmd.NArg() == 0 {
-		return fmt.Errorf("either --all flag or path is required")
+// checkTagCollisions returns an error if the tag format gives an artifact in
+// paths the same tag as another released artifact for the same version, so
+// that their releases could not be told apart. Artifacts whose tags cannot be
+// formatted are skipped; preparing them reports the error.
+func checkTagCollisions(cfg *config.Config, artifacts map[string]*state.Artifact, paths []string) error {
+	tags := make(map[string]string)
+	for path, artifact := range artifacts {
+		if artifact.Release == nil {
+			continue
+		}
+		if tag, err := artifactTag(cfg, path, artifact, "0.0.0"); err == nil {
+			tags[path] = tag
+		}
 	}
+	check := make(map[string]bool)
+	for _, path := range paths {
+		check[filepath.Clean(path)] = true
+	}
+	var errs []string
+	for tag, owners := range release.SharedTags(tags) {
+		for _, path := range owners {
+			if check[path] {
+				errs = append(errs, fmt.Sprintf("%s share the tag %s", strings.Join(owners, ", "), tag))
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("the tag format does not give each artifact its own tags: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
 
-	if all {
-		artifacts, err := state.LoadAll()
-		if err != nil {
-			return fmt.Errorf("failed to load artifacts: %w", err)
+// artifactName returns the package name from the artifact's language
+// metadata, or an empty string if none is set.
+func artifactName(artifact *state.Artifact) string {
+	l := artifact.Language
+	switch {
+	case l == nil:
+		return ""
+	case l.Go != nil && l.Go.Module != "":
+		parts := strings.Split(l.Go.Module, "/")
+		name := parts[len(parts)-1]
+		if majorVersionRegex.MatchString(name) && len(parts) > 1 {
+			name = parts[len(parts)-2]
 		}
+		return name
+	case l.Python != nil && l.Python.Package != "":
+		return l.Python.Package
+	case l.Rust != nil && l.Rust.Crate != "":
+		return l.Rust.Crate
+	case l.Dart != nil && l.Dart.Package != "":
+		return l.Dart.Package
+	}
+	return ""
+}
 
-		var tagged bool
-		for path, artifact := range artifacts {
-			if artifact.Release != nil && artifact.Release.Prepared != nil {
-				fmt.Printf("Releasing %s %s...\n", path, artifact.Release.Prepared.Tag)
-				if err := createGitTag(artifact.Release.Prepared.Tag, artifact.Release.Prepared.Commit); err != nil {
-					return fmt.Errorf("failed to create git tag for %s: %w", path, err)
-				}
-				fmt.Println("  - Creating git tag...")
+// versionScheme returns the versioning scheme for the artifact: PEP 440 for
+// Python packages and SemVer otherwise.
+func versionScheme(artifact *state.Artifact) release.Scheme {
+	if artifact.Language != nil && artifact.Language.Python != nil {
+		return release.PEP440
+	}
+	return release.SemVer
+}
 
-				// Add to history before clearing prepared
-				artifact.Release.History = append(artifact.Release.History, *artifact.Release.Prepared)
-				artifact.Release.Version = artifact.Release.Prepared.Tag
-				artifact.Release.Prepared = nil
-				tagged = true
+// majorVersionRegex matches the major version suffix of a Go module path.
+var majorVersionRegex = regexp.MustCompile(`^v[0-9]+$`)
 
-				if err := artifact.Save(path); err != nil {
-					return fmt.Errorf("failed to save artifact state: %w", err)
-				}
-				runYamlFmt(filepath.Join(path, ".librarian.yaml"))
-				fmt.Println("  - Done.")
-			}
+// lastReleaseTag returns the tag of the most recent release in history, or
+// an empty string if there is none. If stable is true, prereleases are
+// ignored.
+func lastReleaseTag(history []state.ReleaseInfo, stable bool) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if stable && release.HasPrerelease(history[i].Version) {
+			continue
 		}
+		return history[i].Tag
+	}
+	return ""
+}
 
-		if !tagged {
-			fmt.Println("No artifacts to release.")
-			return nil
-		}
+// releaseLine returns the current version and release history that releases
+// prepared on branch belong to: those of the maintenance branch if r tracks
+// the branch, and the artifact's main version and history otherwise.
+func releaseLine(r *state.ReleaseState, branch string) (version *string, history *[]state.ReleaseInfo) {
+	if b := r.Branches[branch]; b != nil {
+		return &b.Version, &b.History
+	}
+	return &r.Version, &r.History
+}
 
-		fmt.Println("Release complete.")
+// startMaintenanceBranch starts tracking releases of the artifact at path on
+// the maintenance branch, from the latest release tag in the branch's version
+// series. It does nothing if the branch is already tracked.
+func startMaintenanceBranch(cfg *config.Config, path string, artifact *state.Artifact, branch string) error {
+	if artifact.Release.Branches[branch] != nil {
 		return nil
 	}
+	series, err := release.ParseSeries(branch)
+	if err != nil {
+		return err
+	}
+	version, err := latestSeriesVersion(cfg, path, artifact, series)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		return fmt.Errorf("no release of the %s series of %s is tagged; maintenance branch %s has no release to continue", series, path, branch)
+	}
+	if artifact.Release.Branches == nil {
+		artifact.Release.Branches = make(map[string]*state.BranchState)
+	}
+	artifact.Release.Branches[branch] = &state.BranchState{Version: version}
+	fmt.Printf("    Tracking maintenance branch %s from %s\n", branch, version)
+	return nil
+}
 
-	if path == "" {
-		return fmt.Errorf("path is required (or use --all)")
+// latestSeriesVersion returns the highest stable version of the artifact at
+// path in series, from the repository's release tags, or an empty string if
+// there is none.
+func latestSeriesVersion(cfg *config.Config, path string, artifact *state.Artifact, series release.Series) (string, error) {
+	var format string
+	if cfg.Release != nil {
+		format = cfg.Release.TagFormat
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	versions, err := release.TagVersions(format, release.TagFields{
+		ID:   filepath.Base(path),
+		Name: artifactName(artifact),
+		Path: path,
+	})
+	if err != nil {
+		return "", err
+	}
+	scheme := versionScheme(artifact)
+	var latest *release.Version
+	for _, s := range versions {
+		v, err := release.ParseVersionScheme(s, scheme)
+		if err != nil || v.IsPrerelease() || !series.Contains(v) {
+			continue
+		}
+		if latest == nil || v.Compare(*latest) > 0 {
+			latest = &v
+		}
+	}
+	if latest == nil {
+		return "", nil
 	}
+	if scheme == release.SemVer {
+		// Versions are recorded with a leading "v", as prepare writes them.
+		latest.Prefix = "v"
+	}
+	return latest.String(), nil
+}
 
-	artifact, err := state.Load(path)
+// applyLanguageFlag sets the language-specific field of artifact given by a
+// --language flag in the format "LANG:KEY=VALUE".
+func applyLanguageFlag(artifact *state.Artifact, flag string) error {
+	lang, key, value, err := parseLanguageFlag(flag)
 	if err != nil {
-		return fmt.Errorf("failed to load artifact at %s: %w", path, err)
+		return err
 	}
 
-	if artifact.Release == nil || artifact.Release.Prepared == nil {
-		return fmt.Errorf("no release prepared for artifact at %s", path)
+	if artifact.Language == nil {
+		artifact.Language = &state.LanguageState{}
 	}
 
-	fmt.Printf("Releasing %s %s...\n", path, artifact.Release.Prepared.Tag)
-	if err := createGitTag(artifact.Release.Prepared.Tag, artifact.Release.Prepared.Commit); err != nil {
-		return fmt.Errorf("failed to create git tag for %s: %w", path, err)
+	switch lang {
+	case "go":
+		if artifact.Language.Go == nil {
+			artifact.Language.Go = &state.GoLanguage{}
+		}
+		switch key {
+		case "module":
+			artifact.Language.Go.Module = value
+			fmt.Printf("Set Go module: %s\n", value)
+		default:
+			return fmt.Errorf("unknown Go property: %s (expected 'module')", key)
+		}
+	case "python":
+		if artifact.Language.Python == nil {
+			artifact.Language.Python = &state.PythonLanguage{}
+		}
+		switch key {
+		case "package":
+			artifact.Language.Python.Package = value
+			fmt.Printf("Set Python package: %s\n", value)
+		default:
+			return fmt.Errorf("unknown Python property: %s (expected 'package')", key)
+		}
+	case "rust":
+		if artifact.Language.Rust == nil {
+			artifact.Language.Rust = &state.RustLanguage{}
+		}
+		switch key {
+		case "crate":
+			artifact.Language.Rust.Crate = value
+			fmt.Printf("Set Rust crate: %s\n", value)
+		default:
+			return fmt.Errorf("unknown Rust property: %s (expected 'crate')", key)
+		}
+	case "dart":
+		if artifact.Language.Dart == nil {
+			artifact.Language.Dart = &state.DartLanguage{}
+		}
+		switch key {
+		case "package":
+			artifact.Language.Dart.Package = value
+			fmt.Printf("Set Dart package: %s\n", value)
+		default:
+			return fmt.Errorf("unknown Dart property: %s (expected 'package')", key)
+		}
+	default:
+		return fmt.Errorf("unknown language: %s (expected go, python, rust, or dart)", lang)
 	}
-	fmt.Println("  - Creating git tag...")
+	return nil
+}
 
-	// Add to history before clearing prepared
-	artifact.Release.History = append(artifact.Release.History, *artifact.Release.Prepared)
-	artifact.Release.Version = artifact.Release.Prepared.Tag
-	artifact.Release.Prepared = nil
+// packageNameKeys are the --language keys of the package name for each
+// language.
+var packageNameKeys = map[string]string{
+	"go":     "module",
+	"python": "package",
+	"rust":   "crate",
+	"dart":   "package",
+}
 
-	if err := artifact.Save(path); err != nil {
-		return fmt.Errorf("failed to save artifact state: %w", err)
+// setDefaultPackageName records the package name of the new artifact at path
+// in language when it has no language metadata: the name in the artifact's
+// go.mod, Cargo.toml, or pubspec.yaml, or else, except for Go modules, the
+// directory name.
+func setDefaultPackageName(language, path string, artifact *state.Artifact) error {
+	key, ok := packageNameKeys[language]
+	if !ok || artifact.Language != nil {
+		return nil
 	}
-	runYamlFmt(filepath.Join(path, ".librarian.yaml"))
-	fmt.Println("  - Done.")
-
-	fmt.Println("Release complete.")
-	return nil
+	graph, err := deps.Load([]string{path})
+	if err != nil {
+		return err
+	}
+	name := graph.Name(path)
+	if name == "" && language != "go" {
+		name = filepath.Base(filepath.Clean(path))
+	}
+	if name == "" {
+		return nil
+	}
+	return applyLanguageFlag(artifact, fmt.Sprintf("%s:%s=%s", language, key, name))
 }
 
-func createGitTag(tag, commit string) error {
-	cmd := exec.Command("git", "tag", tag, commit)
-	return cmd.Run()
+// artifactLanguage returns the language of the artifact: the language of its
+// language metadata if set, and the repository language otherwise.
+func artifactLanguage(cfg *config.Config, artifact *state.Artifact) string {
+	if l := artifact.Language; l != nil {
+		switch {
+		case l.Go != nil:
+			return "go"
+		case l.Python != nil:
+			return "python"
+		case l.Rust != nil:
+			return "rust"
+		case l.Dart != nil:
+			return "dart"
+		}
+	}
+	return cfg.Librarian.Language
 }
 
 // parseLanguageFlag parses a string in the format "LANG:KEY=VALUE" and returns the language, key, and value.
@@ -1084,13 +1651,3 @@ func getLibrarianVersion() (string, error) {
 	// Dummy version for prototype
 	return "v0.1.0-dummy", nil
 }
-
-// runYamlFmt runs yamlfmt on the given file.
-func runYamlFmt(file string) {
-	cmd := exec.Command("yamlfmt", file)
-	if err := cmd.Run(); err != nil {
-		// If yamlfmt is not installed, we don't want to fail.
-		// We'll just log a warning.
-		log.Printf("failed to run yamlfmt on %s: %v", file, err)
-	}
-}
@@ -0,0 +1,100 @@
+package librarian
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+// TestAddCommandArgs checks that every argument after the path is an API.
+// Declared arguments are not included in cmd.Args(), so slicing off the
+// path again would drop the first API.
+func TestAddCommandArgs(t *testing.T) {
+	chdir(t, t.TempDir())
+	googleapis := t.TempDir()
+	writeFiles(t, googleapis, map[string]string{
+		"google/cloud/secretmanager/v1/BUILD.bazel":      "",
+		"google/cloud/secretmanager/v1beta2/BUILD.bazel": "",
+	})
+	writeFiles(t, ".librarian", map[string]string{"config.yaml": `librarian:
+  version: v0.1.0
+  language: go
+generate:
+  container:
+    image: go-generator
+    tag: v1
+  googleapis:
+    repo: github.com/googleapis/googleapis
+    ref: c288189b43c016dd3cf1ec73ce3cadee8b732f07
+  discovery:
+    repo: github.com/googleapis/discovery-artifact-manager
+    ref: f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0
+`})
+
+	writeFiles(t, "secretmanager", map[string]string{"doc.go": "package secretmanager\n"})
+
+	args := []string{"librarian", "add", "--googleapis-dir", googleapis, "secretmanager",
+		"google/cloud/secretmanager/v1", "google/cloud/secretmanager/v1beta2"}
+	if err := NewApp().Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	artifact, err := state.Load("secretmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, api := range artifact.Generate.APIs {
+		got = append(got, api.Path)
+	}
+	want := []string{"google/cloud/secretmanager/v1", "google/cloud/secretmanager/v1beta2"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("APIs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestRequiredArgs checks that commands with a declared argument detect a
+// missing argument, since cmd.NArg() does not count declared arguments.
+func TestRequiredArgs(t *testing.T) {
+	chdir(t, t.TempDir())
+	for _, test := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"librarian", "add"}, "path is required"},
+		{[]string{"librarian", "prepare"}, "path is required"},
+		{[]string{"librarian", "config", "update"}, "key or --all is required"},
+	} {
+		t.Run(strings.Join(test.args[1:], " "), func(t *testing.T) {
+			err := NewApp().Run(context.Background(), test.args)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Run(%q) error = %v, want %q", test.args, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigUpdateKey(t *testing.T) {
+	for _, test := range []struct {
+		key, want string
+	}{
+		{"generate.container", "generate.container"},
+		{"generator.image", "generate.container"},
+		{"generator.googleapis", "generate.googleapis"},
+		{"generator.discovery", "generate.discovery"},
+		{"all", "all"},
+	} {
+		got, err := configUpdateKey(test.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("configUpdateKey(%q) = %q, want %q", test.key, got, test.want)
+		}
+	}
+	if _, err := configUpdateKey("generate.image"); err == nil {
+		t.Error("configUpdateKey() for an unknown key succeeded, want error")
+	}
+}
@@ -0,0 +1,458 @@
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/deps"
+	"github.com/julieqiu/exp/librarian/internal/legacy"
+	"github.com/julieqiu/exp/librarian/internal/release"
+	"github.com/julieqiu/exp/librarian/internal/state"
+	"github.com/urfave/cli/v3"
+)
+
+// configFile is the location of the repository configuration, relative to
+// the repository root.
+const configFile = ".librarian/config.yaml"
+
+// migration is the result of converting a legacy state file.
+type migration struct {
+	config    *config.Config
+	artifacts map[string]*state.Artifact // by artifact directory
+	unmapped  []string                   // fields that could not be converted
+}
+
+func migrateCommand(ctx context.Context, cmd *cli.Command) error {
+	s, err := legacy.Load(legacy.StateFile)
+	if err != nil {
+		return err
+	}
+
+	// The legacy format also had a .librarian/config.yaml, with settings
+	// that have no equivalent. It is replaced by the new configuration.
+	var configFields []string
+	data, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		isLegacy, err := legacy.IsLegacyConfig(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", configFile, err)
+		}
+		if !isLegacy {
+			return fmt.Errorf("%s is already in the current format", configFile)
+		}
+		configFields, err = legacy.ConfigFields(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", configFile, err)
+		}
+	}
+
+	language := cmd.String("language")
+	if language == "" {
+		language = imageLanguage(s.Image)
+		if language == "" {
+			return fmt.Errorf("cannot determine the language from image %q; use --language", s.Image)
+		}
+	}
+	m, err := migrateState(s, language)
+	if err != nil {
+		return err
+	}
+	for _, f := range configFields {
+		m.unmapped = append(m.unmapped, fmt.Sprintf("config.yaml: %s", f))
+	}
+
+	var paths []string
+	for p := range m.artifacts {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	// Check every artifact before writing any, so that a failed migration
+	// leaves the repository unchanged.
+	for _, p := range paths {
+		if info, err := os.Stat(p); err != nil || !info.IsDir() {
+			return fmt.Errorf("artifact directory %s does not exist", p)
+		}
+		if _, err := os.Stat(state.File(p)); err == nil {
+			return fmt.Errorf("%s already exists; remove it to migrate %s", state.File(p), p)
+		}
+	}
+
+	if !cmd.Bool("dry-run") {
+		for _, p := range paths {
+			if err := m.artifacts[p].Save(p); err != nil {
+				return fmt.Errorf("failed to save artifact state for %s: %w", p, err)
+			}
+		}
+		// Remove the legacy configuration first, so that none of its
+		// settings are carried into the new file.
+		if err := os.Remove(configFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := m.config.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+
+	fmt.Printf("Migrated %d libraries from %s to .librarian.yaml files\n", len(paths), legacy.StateFile)
+	fmt.Printf("Wrote %s for %s\n", configFile, language)
+	if len(m.unmapped) > 0 {
+		fmt.Println("The following fields could not be migrated:")
+		for _, u := range m.unmapped {
+			fmt.Printf("  - %s\n", u)
+		}
+	}
+	if !cmd.Bool("dry-run") {
+		fmt.Printf("Review the new files, then delete %s.\n", legacy.StateFile)
+	}
+	return nil
+}
+
+// migrateState converts the legacy state s of a repository for language into
+// the repository configuration and the state of each artifact.
+func migrateState(s *legacy.State, language string) (*migration, error) {
+	librarianVersion, err := getLibrarianVersion()
+	if err != nil {
+		return nil, err
+	}
+	m := &migration{artifacts: make(map[string]*state.Artifact)}
+	unmapped := func(format string, args ...any) {
+		m.unmapped = append(m.unmapped, fmt.Sprintf(format, args...))
+	}
+	for _, k := range legacy.Keys(s.Other) {
+		unmapped("state.yaml: %s", k)
+	}
+
+	container := parseImage(s.Image)
+	if container.Digest != "" && container.Tag == "" {
+		unmapped("image: %s is pinned by digest only; set generate.container.tag to its tag", s.Image)
+	}
+	var commits []string
+	for _, l := range s.Libraries {
+		if l.LastGeneratedCommit != "" {
+			commits = append(commits, l.LastGeneratedCommit)
+		}
+	}
+	m.config = &config.Config{
+		Librarian: config.LibrarianConfig{
+			Version:  librarianVersion,
+			Language: language,
+		},
+		Generate: &config.GenerateConfig{
+			Container: container,
+			Googleapis: &config.RepoConfig{
+				Repo: "github.com/googleapis/googleapis",
+				Ref:  mostCommon(commits),
+			},
+		},
+		Release: &config.ReleaseConfig{},
+	}
+
+	libraries := make(map[string]*legacy.Library) // by artifact directory
+	var formats []string
+	for _, l := range s.Libraries {
+		dir := libraryDir(l)
+		if dir == "" {
+			unmapped("%s: no source_roots; library not migrated", l.ID)
+			continue
+		}
+		if other, ok := libraries[dir]; ok {
+			unmapped("%s: directory %s is already used by %s; library not migrated", l.ID, dir, other.ID)
+			continue
+		}
+		artifact, err := migrateLibrary(m.config, l, dir, unmapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate %s: %w", l.ID, err)
+		}
+		libraries[dir] = l
+		m.artifacts[dir] = artifact
+		if f := tagFormat(l, dir, artifact); f != "" {
+			formats = append(formats, f)
+		}
+	}
+
+	// New artifacts are added next to the existing ones.
+	var parents []string
+	for dir := range m.artifacts {
+		if parent := path.Dir(dir); parent != "." {
+			parents = append(parents, parent+"/")
+		} else {
+			parents = append(parents, "")
+		}
+	}
+	m.config.Generate.Dir = mostCommon(parents)
+
+	// The tag format is set for the whole repository. Libraries whose tags
+	// it does not reproduce are reported.
+	m.config.Release.TagFormat = mostCommon(formats)
+	var dirs []string
+	for dir := range libraries {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		l, artifact := libraries[dir], m.artifacts[dir]
+		if !release.Released(artifact.Release.Version) {
+			continue
+		}
+		want := l.Tag(artifact.Release.Version)
+		got, err := artifactTag(m.config, dir, artifact, artifact.Release.Version)
+		if err != nil {
+			unmapped("%s: tag_format %q: %v", l.ID, l.TagFormat, err)
+		} else if l.TagFormat == "" {
+			unmapped("%s: no tag_format, so no release is recorded; new releases will be tagged %s", l.ID, got)
+		} else if got != want {
+			unmapped("%s: tag_format %q; releases will be tagged %s instead of %s", l.ID, l.TagFormat, got, want)
+		}
+	}
+	return m, nil
+}
+
+// migrateLibrary returns the state of the artifact in dir for the legacy
+// library l. Fields that cannot be converted are reported with unmapped.
+func migrateLibrary(cfg *config.Config, l *legacy.Library, dir string, unmapped func(string, ...any)) (*state.Artifact, error) {
+	language := cfg.Librarian.Language
+	for _, k := range legacy.Keys(l.Other) {
+		unmapped("%s: %s", l.ID, k)
+	}
+	// The legacy library ID is the package name, except for Go, whose
+	// module path is read from go.mod.
+	name := l.ID
+	if language == "go" {
+		graph, err := deps.Load([]string{dir})
+		if err != nil {
+			return nil, err
+		}
+		name = graph.Name(dir)
+	}
+	artifact := &state.Artifact{Language: languageState(language, name)}
+
+	version := l.Version
+	if !release.Released(version) {
+		version = release.NoVersion
+	} else if versionScheme(artifact) == release.SemVer && !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	artifact.Release = &state.ReleaseState{Version: version}
+	if release.Released(version) && l.TagFormat != "" {
+		// Record the current release, so that the next release covers
+		// the commits since its tag.
+		artifact.Release.History = []state.ReleaseInfo{{Version: version, Tag: l.Tag(version)}}
+	}
+
+	if len(l.APIs) > 0 {
+		c := cfg.Generate.Container
+		g := &state.GenerateState{
+			Commit:    l.LastGeneratedCommit,
+			Librarian: cfg.Librarian.Version,
+			Container: state.ContainerState{Image: c.Image, Tag: c.Tag, Digest: c.Digest},
+			Googleapis: state.GoogleapisState{
+				Repo: cfg.Generate.Googleapis.Repo,
+				Ref:  l.LastGeneratedCommit,
+			},
+		}
+		for _, api := range l.APIs {
+			g.APIs = append(g.APIs, state.API{Path: api.Path, ServiceYaml: api.ServiceConfig})
+			for _, k := range legacy.Keys(api.Other) {
+				unmapped("%s: apis[%s].%s", l.ID, api.Path, k)
+			}
+		}
+		artifact.Generate = g
+	}
+
+	snippets := path.Join(snippetsDir, dir)
+	for _, root := range l.SourceRoots {
+		root = path.Clean(root)
+		if !within(root, dir) && root != snippets {
+			unmapped("%s: source_roots entry %s is outside %s", l.ID, root, dir)
+		}
+	}
+
+	cs := &state.ConfigState{}
+	for _, re := range l.PreserveRegex {
+		p, ok := keepPattern(re, dir)
+		if !ok {
+			unmapped("%s: preserve_regex %q", l.ID, re)
+			continue
+		}
+		cs.Keep = append(cs.Keep, p)
+	}
+	// Generation replaces the files of the artifact directory, so removing
+	// them beforehand is only needed for files outside it.
+	for _, re := range l.RemoveRegex {
+		if !insideAny(re, dir, snippets) {
+			unmapped("%s: remove_regex %q", l.ID, re)
+		}
+	}
+	// Paths outside the artifact directory are never released.
+	for _, p := range l.ReleaseExcludePaths {
+		p = path.Clean(p)
+		if dir == "." {
+			cs.Exclude = append(cs.Exclude, p)
+		} else if rel, ok := strings.CutPrefix(p, dir+"/"); ok {
+			cs.Exclude = append(cs.Exclude, rel)
+		}
+	}
+	if len(cs.Keep)+len(cs.Exclude) > 0 {
+		artifact.Config = cs
+	}
+	return artifact, nil
+}
+
+// libraryDir returns the artifact directory of the legacy library l: its
+// source root, or the directory containing all of its source roots. Go
+// snippet directories are not counted. It returns an empty string if l has
+// no source roots.
+func libraryDir(l *legacy.Library) string {
+	var roots []string
+	for _, root := range l.SourceRoots {
+		root = path.Clean(root)
+		if !within(root, snippetsDir) {
+			roots = append(roots, root)
+		}
+	}
+	if len(roots) == 0 {
+		return ""
+	}
+	dir := roots[0]
+	for _, root := range roots[1:] {
+		for !within(root, dir) {
+			dir = path.Dir(dir)
+		}
+	}
+	return dir
+}
+
+// within reports whether the slash-separated path p is dir or inside it.
+func within(p, dir string) bool {
+	return dir == "." || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// insideAny reports whether the legacy path regular expression re only
+// matches paths inside one of dirs.
+func insideAny(re string, dirs ...string) bool {
+	p, _, ok := legacy.Glob(re)
+	if !ok {
+		// The expression is a path followed by other syntax.
+		p = strings.TrimPrefix(re, "^")
+	}
+	for _, dir := range dirs {
+		if p == dir && ok || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// keepPattern converts a legacy preserve_regex expression into a keep
+// pattern relative to the artifact directory dir. Expressions anchored at the
+// repository root must name a path inside dir. Unanchored expressions, which
+// match at any depth, are taken to be relative to dir.
+func keepPattern(re, dir string) (string, bool) {
+	p, anchored, ok := legacy.Glob(re)
+	if !ok {
+		return "", false
+	}
+	if dir == "." {
+		return p, true
+	}
+	if rel, ok := strings.CutPrefix(p, dir+"/"); ok {
+		return rel, true
+	}
+	if anchored || p == dir {
+		return "", false
+	}
+	return p, true
+}
+
+// tagFormat returns the tag format equivalent to the legacy tag format of
+// l, whose {id} is replaced by the placeholder with the same value for the
+// artifact in dir. It returns an empty string if there is none.
+func tagFormat(l *legacy.Library, dir string, artifact *state.Artifact) string {
+	f := l.TagFormat
+	if f == "" || !strings.Contains(f, "{id}") {
+		return f
+	}
+	var placeholder string
+	switch l.ID {
+	case dir:
+		placeholder = "{path}"
+	case artifactName(artifact):
+		placeholder = "{name}"
+	case path.Base(dir):
+		placeholder = "{id}"
+	default:
+		return ""
+	}
+	return strings.ReplaceAll(f, "{id}", placeholder)
+}
+
+// languageState returns the language metadata recording the package name of
+// an artifact in language, or nil if there is none.
+func languageState(language, name string) *state.LanguageState {
+	if name == "" {
+		return nil
+	}
+	switch language {
+	case "go":
+		return &state.LanguageState{Go: &state.GoLanguage{Module: name}}
+	case "python":
+		return &state.LanguageState{Python: &state.PythonLanguage{Package: name}}
+	case "rust":
+		return &state.LanguageState{Rust: &state.RustLanguage{Crate: name}}
+	case "dart":
+		return &state.LanguageState{Dart: &state.DartLanguage{Package: name}}
+	}
+	return nil
+}
+
+// parseImage splits a legacy image reference, such as
+// "example.com/librarian-go:v1" or "example.com/librarian-go@sha256:...",
+// into the container configuration.
+func parseImage(image string) *config.ContainerConfig {
+	c := &config.ContainerConfig{}
+	image, c.Digest, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, c.Tag = image[:i], image[i+1:]
+	}
+	c.Image = image
+	return c
+}
+
+// imageLanguage returns the language of a legacy generator image, from the
+// words of its name, such as "go" in ".../librarian-go" or "python" in
+// ".../python-librarian-generator". It returns an empty string if the
+// language is not recognized.
+func imageLanguage(image string) string {
+	c := parseImage(image)
+	name := c.Image[strings.LastIndex(c.Image, "/")+1:]
+	for _, word := range strings.Split(name, "-") {
+		if _, ok := packageNameKeys[word]; ok {
+			return word
+		}
+	}
+	return ""
+}
+
+// mostCommon returns the most frequent of values, preferring the first in
+// sort order on a tie. It returns an empty string if values is empty.
+func mostCommon(values []string) string {
+	counts := make(map[string]int)
+	for _, v := range values {
+		counts[v]++
+	}
+	var best string
+	for v, n := range counts {
+		if n > counts[best] || n == counts[best] && v < best {
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,177 @@
+package librarian
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/legacy"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+func loadLegacyState(t *testing.T, language string) *legacy.State {
+	t.Helper()
+	s, err := legacy.Load(filepath.Join("..", "..", "testdata", language, "state.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func hasUnmapped(unmapped []string, prefix string) bool {
+	for _, u := range unmapped {
+		if strings.HasPrefix(u, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMigrateStateGo(t *testing.T) {
+	m, err := migrateState(loadLegacyState(t, "go"), "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.artifacts) != 182 {
+		t.Errorf("migrated %d artifacts, want 182", len(m.artifacts))
+	}
+	wantConfig := &config.Config{
+		Librarian: config.LibrarianConfig{Version: "v0.1.0-dummy", Language: "go"},
+		Generate: &config.GenerateConfig{
+			Container: &config.ContainerConfig{
+				Image:  "us-central1-docker.pkg.dev/cloud-sdk-librarian-prod/images-prod/librarian-go",
+				Digest: "sha256:496591304b0d24ab57ea5f2c8a7870921713edab7f6fd002c5b2e78a8d6de396",
+			},
+			Googleapis: &config.RepoConfig{
+				Repo: "github.com/googleapis/googleapis",
+				Ref:  "c288189b43c016dd3cf1ec73ce3cadee8b732f07",
+			},
+		},
+		Release: &config.ReleaseConfig{TagFormat: "{path}/v{version}"},
+	}
+	if diff := cmp.Diff(wantConfig, m.config); diff != "" {
+		t.Errorf("config mismatch (-want +got):\n%s", diff)
+	}
+
+	got := m.artifacts["accessapproval"]
+	want := &state.Artifact{
+		Generate: &state.GenerateState{
+			APIs:      []state.API{{Path: "google/cloud/accessapproval/v1", ServiceYaml: "accessapproval_v1.yaml"}},
+			Commit:    "c288189b43c016dd3cf1ec73ce3cadee8b732f07",
+			Librarian: "v0.1.0-dummy",
+			Container: state.ContainerState{
+				Image:  "us-central1-docker.pkg.dev/cloud-sdk-librarian-prod/images-prod/librarian-go",
+				Digest: "sha256:496591304b0d24ab57ea5f2c8a7870921713edab7f6fd002c5b2e78a8d6de396",
+			},
+			Googleapis: state.GoogleapisState{
+				Repo: "github.com/googleapis/googleapis",
+				Ref:  "c288189b43c016dd3cf1ec73ce3cadee8b732f07",
+			},
+		},
+		Release: &state.ReleaseState{
+			Version: "v1.8.8",
+			History: []state.ReleaseInfo{{Version: "v1.8.8", Tag: "accessapproval/v1.8.8"}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("accessapproval mismatch (-want +got):\n%s", diff)
+	}
+
+	if a := m.artifacts["bigquery"]; a.Config == nil || !cmp.Equal(a.Config.Exclude, []string{"benchmarks", "storage/managedwriter/testdata", "v2"}) {
+		t.Errorf("bigquery config = %+v, want release_exclude_paths inside bigquery", a.Config)
+	}
+	if _, ok := m.artifacts["."]; !ok {
+		t.Errorf("root-module was not migrated to the repository root")
+	}
+	for _, prefix := range []string{
+		"image: ",
+		"root-module: tag_format",
+		"bigquery/v2: tag_format",
+		"gkerecommender: remove_regex",
+		"datastore: no tag_format",
+	} {
+		if !hasUnmapped(m.unmapped, prefix) {
+			t.Errorf("unmapped fields do not include %q:\n%s", prefix, strings.Join(m.unmapped, "\n"))
+		}
+	}
+}
+
+func TestMigrateStatePython(t *testing.T) {
+	m, err := migrateState(loadLegacyState(t, "python"), "python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.artifacts) != 231 {
+		t.Errorf("migrated %d artifacts, want 231", len(m.artifacts))
+	}
+	if got, want := m.config.Release.TagFormat, "{name}-v{version}"; got != want {
+		t.Errorf("tag format = %q, want %q", got, want)
+	}
+	if got, want := m.config.Generate.Dir, "packages/"; got != want {
+		t.Errorf("generate dir = %q, want %q", got, want)
+	}
+
+	got := m.artifacts["packages/google-ads-admanager"]
+	if got.Release.Version != "0.6.0" {
+		t.Errorf("version = %q, want %q", got.Release.Version, "0.6.0")
+	}
+	wantKeep := []string{
+		"CHANGELOG.md",
+		"docs/CHANGELOG.md",
+		"docs/README.rst",
+		"samples/README.txt",
+		"scripts/client-post-processing",
+		"samples/snippets/README.rst",
+		"tests/system",
+	}
+	if diff := cmp.Diff(&state.ConfigState{Keep: wantKeep}, got.Config); diff != "" {
+		t.Errorf("config mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(&state.LanguageState{Python: &state.PythonLanguage{Package: "google-ads-admanager"}}, got.Language); diff != "" {
+		t.Errorf("language mismatch (-want +got):\n%s", diff)
+	}
+	wantUnmapped := []string{
+		`google-cloud-bigquery-storage: preserve_regex "docs/.*/library.rst"`,
+		`google-cloud-bigquery-storage: preserve_regex "tests/unit/.*.py"`,
+	}
+	if diff := cmp.Diff(wantUnmapped, m.unmapped); diff != "" {
+		t.Errorf("unmapped mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLibraryDir(t *testing.T) {
+	for _, test := range []struct {
+		roots []string
+		want  string
+	}{
+		{[]string{"accessapproval", "internal/generated/snippets/accessapproval"}, "accessapproval"},
+		{[]string{"packages/google-cloud-dlp/"}, "packages/google-cloud-dlp"},
+		{[]string{"civil", "internal", "rpcreplay"}, "."},
+		{[]string{"a/b/c", "a/b/d"}, "a/b"},
+		{nil, ""},
+	} {
+		if got := libraryDir(&legacy.Library{SourceRoots: test.roots}); got != test.want {
+			t.Errorf("libraryDir(%q) = %q, want %q", test.roots, got, test.want)
+		}
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	for _, test := range []struct {
+		image string
+		want  *config.ContainerConfig
+	}{
+		{"example.com/images/python-librarian-generator:latest", &config.ContainerConfig{Image: "example.com/images/python-librarian-generator", Tag: "latest"}},
+		{"example.com/images/librarian-go@sha256:abc", &config.ContainerConfig{Image: "example.com/images/librarian-go", Digest: "sha256:abc"}},
+		{"localhost:5000/librarian-go", &config.ContainerConfig{Image: "localhost:5000/librarian-go"}},
+	} {
+		if diff := cmp.Diff(test.want, parseImage(test.image)); diff != "" {
+			t.Errorf("parseImage(%q) mismatch (-want +got):\n%s", test.image, diff)
+		}
+	}
+	if got := imageLanguage("example.com/images/librarian-go@sha256:abc"); got != "go" {
+		t.Errorf("imageLanguage() = %q, want %q", got, "go")
+	}
+}
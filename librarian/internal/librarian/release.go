@@ -0,0 +1,572 @@
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/contents"
+	"github.com/julieqiu/exp/librarian/internal/journal"
+	"github.com/julieqiu/exp/librarian/internal/publish"
+	"github.com/julieqiu/exp/librarian/internal/release"
+	"github.com/julieqiu/exp/librarian/internal/state"
+	"github.com/urfave/cli/v3"
+)
+
+func releaseCommand(ctx context.Context, cmd *cli.Command) error {
+	all := cmd.Bool("all")
+	path := cmd.StringArg("path")
+	dryRun := cmd.Bool("dry-run")
+	local := cmd.Bool("local")
+	if path != "" {
+		path = filepath.Clean(path)
+	}
+
+	if cmd.Bool("rollback") {
+		return rollbackRelease()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cmd.Bool("list-files") {
+		if !all && path == "" {
+			return fmt.Errorf("either --all flag or path is required")
+		}
+		return listReleaseFiles(cfg, path, all)
+	}
+
+	// An existing journal means a previous release did not finish.
+	j, err := journal.Load()
+	if err != nil {
+		return err
+	}
+	if j != nil {
+		if err := checkResume(j, path, local); err != nil {
+			return err
+		}
+		fmt.Printf("Resuming incomplete release recorded in %s\n", journal.File)
+		if dryRun {
+			printPlannedReleases(ctx, j, local || j.Local)
+			return nil
+		}
+		return runRelease(ctx, cfg, j, local || j.Local)
+	}
+
+	if !all && path == "" {
+		return fmt.Errorf("either --all flag or path is required")
+	}
+
+	artifacts := make(map[string]*state.Artifact)
+	if all {
+		artifacts, err = state.LoadAll()
+		if err != nil {
+			return fmt.Errorf("failed to load artifacts: %w", err)
+		}
+	} else {
+		artifact, err := state.Load(path)
+		if err != nil {
+			return fmt.Errorf("failed to load artifact at %s: %w", path, err)
+		}
+		if artifact.Release == nil || artifact.Release.Prepared == nil {
+			return fmt.Errorf("no release prepared for artifact at %s", path)
+		}
+		artifacts[path] = artifact
+	}
+
+	// Release dependencies before the artifacts that depend on them
+	_, order, err := artifactOrder(artifacts)
+	if err != nil {
+		return err
+	}
+
+	j = &journal.Journal{Local: local}
+	for _, path := range order {
+		artifact := artifacts[path]
+		if artifact.Release == nil || artifact.Release.Prepared == nil {
+			continue
+		}
+		// Verify that no tag exists before creating any
+		if err := checkTagAvailable(artifact.Release.Prepared.Tag); err != nil {
+			return fmt.Errorf("cannot release %s: %w", path, err)
+		}
+		if _, _, err := releaseFiles(cfg, path, artifact); err != nil {
+			return fmt.Errorf("cannot release %s: %w", path, err)
+		}
+		j.Entries = append(j.Entries, &journal.Entry{
+			Path:            path,
+			PreviousVersion: previousVersion(artifact.Release),
+			Prepared:        *artifact.Release.Prepared,
+		})
+	}
+	if len(j.Entries) == 0 {
+		fmt.Println("No artifacts to release.")
+		return nil
+	}
+
+	if dryRun {
+		printPlannedReleases(ctx, j, local)
+		return nil
+	}
+
+	if err := j.Save(); err != nil {
+		return err
+	}
+	return runRelease(ctx, cfg, j, local)
+}
+
+// checkResume returns an error if the incomplete release in j cannot be
+// resumed with the given arguments. A path must name the only artifact in the
+// release, and a release that still has tags to push or GitHub releases to
+// create cannot be finished with --local.
+func checkResume(j *journal.Journal, path string, local bool) error {
+	var paths []string
+	for _, e := range j.Entries {
+		paths = append(paths, e.Path)
+	}
+	if path != "" && (len(paths) != 1 || paths[0] != filepath.Clean(path)) {
+		return fmt.Errorf("an incomplete release of %s is recorded in %s; run `librarian release --all` to resume it or `librarian release --rollback` to undo it",
+			strings.Join(paths, ", "), journal.File)
+	}
+	if unpublished := j.Unpublished(); local && len(unpublished) > 0 {
+		return fmt.Errorf("the incomplete release of %s has not been published; resume it without --local or run `librarian release --rollback`",
+			strings.Join(unpublished, ", "))
+	}
+	return nil
+}
+
+// listReleaseFiles prints the files that ship in the release package of the
+// artifact at path, or of every artifact with a release section if all is
+// set. Files left out by an exclude pattern are listed as well, marked
+// "(excluded)".
+func listReleaseFiles(cfg *config.Config, path string, all bool) error {
+	artifacts := make(map[string]*state.Artifact)
+	if all {
+		var err error
+		artifacts, err = state.LoadAll()
+		if err != nil {
+			return fmt.Errorf("failed to load artifacts: %w", err)
+		}
+	} else {
+		artifact, err := state.Load(path)
+		if err != nil {
+			return fmt.Errorf("failed to load artifact at %s: %w", path, err)
+		}
+		artifacts[path] = artifact
+	}
+	var paths []string
+	for p, artifact := range artifacts {
+		if artifact.Release != nil || !all {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		shipped, excluded, err := releaseFiles(cfg, p, artifacts[p])
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if all {
+			fmt.Printf("%s:\n", p)
+		}
+		files := append(slices.Clone(shipped), excluded...)
+		sort.Strings(files)
+		for _, f := range files {
+			if all {
+				fmt.Print("  ")
+			}
+			fmt.Print(filepath.ToSlash(filepath.Join(p, f)))
+			if slices.Contains(excluded, f) {
+				fmt.Print(" (excluded)")
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// releaseFiles returns the files that ship in the release package of the
+// artifact at path, relative to path, and the files left out by the
+// artifact's exclude patterns. The shipped files are those the language's
+// packaging tool would include, less the excluded ones. It returns an error
+// if an excluded file is referenced by the package manifest, since the
+// package could not be built without it.
+func releaseFiles(cfg *config.Config, path string, artifact *state.Artifact) (shipped, excluded []string, err error) {
+	language := artifactLanguage(cfg, artifact)
+	files, err := contents.List(path, language)
+	if err != nil {
+		return nil, nil, err
+	}
+	if artifact.Config == nil || len(artifact.Config.Exclude) == 0 {
+		return files, nil, nil
+	}
+	refs, err := contents.References(path, language, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ref := range refs {
+		if matchAny(artifact.Config.Exclude, ref) {
+			return nil, nil, fmt.Errorf("excluded file %s is referenced by the package manifest", ref)
+		}
+	}
+	for _, f := range files {
+		if matchAny(artifact.Config.Exclude, f) {
+			excluded = append(excluded, f)
+		} else {
+			shipped = append(shipped, f)
+		}
+	}
+	return shipped, excluded, nil
+}
+
+// runRelease releases each artifact in the journal, skipping steps that have
+// already been completed. The journal is removed once every artifact has been
+// released.
+func runRelease(ctx context.Context, cfg *config.Config, j *journal.Journal, local bool) error {
+	pub, err := newPublisher(cfg, local)
+	if err != nil {
+		return err
+	}
+	for _, e := range j.Entries {
+		if err := releaseArtifact(ctx, pub, j, e); err != nil {
+			fmt.Printf("Release interrupted. Run `librarian release` to resume or `librarian release --rollback` to undo.\n")
+			return err
+		}
+	}
+	if err := journal.Remove(); err != nil {
+		return err
+	}
+	fmt.Println("Release complete.")
+	return nil
+}
+
+// releaseArtifact tags the release recorded in e, publishes it with pub, and
+// records it in the artifact state. If pub is nil, the tag is only created
+// locally; if it is a *publish.Git, no hosted release is created. Each
+// completed step is recorded in j, and steps that j records as completed are
+// skipped.
+func releaseArtifact(ctx context.Context, pub publish.Publisher, j *journal.Journal, e *journal.Entry) error {
+	path, prepared := e.Path, e.Prepared
+	if j.Done(path, journal.StepSave) {
+		fmt.Printf("Skipping %s %s (already released)\n", path, prepared.Tag)
+		return nil
+	}
+	fmt.Printf("Releasing %s %s...\n", path, prepared.Tag)
+
+	steps := []struct {
+		step journal.Step
+		msg  string
+		run  func() error
+	}{
+		{journal.StepTag, "Creating git tag", func() error {
+			if err := createGitTag(prepared.Tag, prepared.Commit); err != nil {
+				return fmt.Errorf("failed to create git tag for %s: %w", path, err)
+			}
+			return nil
+		}},
+		{journal.StepPush, "Pushing tag", func() error {
+			if pub == nil {
+				return nil
+			}
+			return pub.PushTag(ctx, prepared.Tag)
+		}},
+		{journal.StepRelease, "Creating GitHub release", func() error {
+			if pub == nil {
+				return nil
+			}
+			return pub.CreateRelease(ctx, newPublishRelease(&prepared))
+		}},
+		{journal.StepSave, "Recording release", func() error {
+			return saveRelease(path, &prepared)
+		}},
+	}
+	for _, s := range steps {
+		if j.Done(path, s.step) {
+			continue
+		}
+		if pub == nil && (s.step == journal.StepPush || s.step == journal.StepRelease) {
+			continue
+		}
+		if _, tagsOnly := pub.(*publish.Git); tagsOnly && s.step == journal.StepRelease {
+			continue
+		}
+		fmt.Printf("  - %s...\n", s.msg)
+		if err := s.run(); err != nil {
+			return err
+		}
+		if err := j.Record(path, s.step); err != nil {
+			return err
+		}
+	}
+	fmt.Println("  - Done.")
+	return nil
+}
+
+// saveRelease records the prepared release in the state of the artifact at
+// path. It does nothing if the release has already been recorded.
+func saveRelease(path string, prepared *state.ReleaseInfo) error {
+	artifact, err := state.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load artifact at %s: %w", path, err)
+	}
+	if artifact.Release == nil || artifact.Release.Prepared == nil {
+		if artifact.Release != nil && isLastRelease(artifact.Release, prepared) {
+			return nil
+		}
+		return fmt.Errorf("no release prepared for artifact at %s", path)
+	}
+	recordRelease(artifact.Release)
+	if err := artifact.Save(path); err != nil {
+		return fmt.Errorf("failed to save artifact state: %w", err)
+	}
+	return nil
+}
+
+// printPlannedReleases prints the operations that releasing the entries of j
+// would perform, leaving out the steps the journal records as done.
+func printPlannedReleases(ctx context.Context, j *journal.Journal, local bool) {
+	pub := &publish.DryRun{Out: os.Stdout}
+	for _, e := range j.Entries {
+		printPlannedRelease(ctx, pub, j, e, local)
+	}
+	fmt.Println("Dry run complete. No changes were made.")
+}
+
+// printPlannedRelease prints the operations that releasing e would perform.
+func printPlannedRelease(ctx context.Context, pub *publish.DryRun, j *journal.Journal, e *journal.Entry, local bool) {
+	if j.Done(e.Path, journal.StepSave) {
+		fmt.Printf("%s %s is already released\n", e.Path, e.Prepared.Tag)
+		return
+	}
+	fmt.Printf("Releasing %s %s...\n", e.Path, e.Prepared.Tag)
+	if !j.Done(e.Path, journal.StepTag) {
+		fmt.Printf("  [dry run] Would create tag %s at %s\n", e.Prepared.Tag, e.Prepared.Commit)
+	}
+	if !local {
+		if !j.Done(e.Path, journal.StepPush) {
+			pub.PushTag(ctx, e.Prepared.Tag)
+		}
+		switch {
+		case j.Done(e.Path, journal.StepRelease):
+		case os.Getenv("GITHUB_TOKEN") != "":
+			pub.CreateRelease(ctx, newPublishRelease(&e.Prepared))
+		default:
+			fmt.Println("  [dry run] Would not create a GitHub release: GITHUB_TOKEN is not set")
+		}
+	}
+	fmt.Printf("  [dry run] Would record %s in %s\n", e.Prepared.Version, filepath.Join(e.Path, ".librarian.yaml"))
+}
+
+// newPublishRelease returns the hosted release for a prepared release.
+func newPublishRelease(prepared *state.ReleaseInfo) *publish.Release {
+	return &publish.Release{
+		Tag:        prepared.Tag,
+		Name:       prepared.Tag,
+		Notes:      prepared.Notes,
+		Prerelease: release.HasPrerelease(prepared.Version),
+	}
+}
+
+// rollbackRelease undoes an incomplete release recorded in the journal. Local
+// tags created by the release are deleted and artifact states that were
+// already updated are restored to their prepared state. Tags that were pushed
+// cannot be rolled back and are reported so they can be removed by hand.
+func rollbackRelease() error {
+	j, err := journal.Load()
+	if err != nil {
+		return err
+	}
+	if j == nil {
+		fmt.Println("No incomplete release to roll back.")
+		return nil
+	}
+
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		e := j.Entries[i]
+		if j.Done(e.Path, journal.StepSave) {
+			if err := restorePrepared(e); err != nil {
+				return err
+			}
+			fmt.Printf("Restored prepared release %s in %s\n", e.Prepared.Version, e.Path)
+		}
+		if j.Done(e.Path, journal.StepPush) {
+			fmt.Printf("Warning: tag %s was pushed; delete it from the remote manually\n", e.Prepared.Tag)
+		}
+		// The tag may have been created without being recorded. The release
+		// checked that it did not exist beforehand, so a tag at the prepared
+		// commit was created by this release.
+		created := j.Done(e.Path, journal.StepTag)
+		if !created {
+			created, err = tagAt(e.Prepared.Tag, e.Prepared.Commit)
+			if err != nil {
+				return err
+			}
+		}
+		if created {
+			if err := deleteGitTag(e.Prepared.Tag); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted tag %s\n", e.Prepared.Tag)
+		}
+	}
+
+	if err := journal.Remove(); err != nil {
+		return err
+	}
+	fmt.Println("Rollback complete.")
+	return nil
+}
+
+// restorePrepared undoes saveRelease for the artifact in e.
+func restorePrepared(e *journal.Entry) error {
+	artifact, err := state.Load(e.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load artifact at %s: %w", e.Path, err)
+	}
+	r := artifact.Release
+	if r == nil || !isLastRelease(r, &e.Prepared) {
+		return nil
+	}
+	version, history := releaseLine(r, e.Prepared.Branch)
+	*history = (*history)[:len(*history)-1]
+	*version = e.PreviousVersion
+	prepared := e.Prepared
+	r.Prepared = &prepared
+	if err := artifact.Save(e.Path); err != nil {
+		return fmt.Errorf("failed to save artifact state: %w", err)
+	}
+	return nil
+}
+
+// newPublisher returns the publisher for release. It returns nil if releases
+// are only tagged locally.
+//
+// The GitHub repository is taken from release.github_repo in the config, or
+// from the URL of the release remote. GitHub releases are created only if
+// the GITHUB_TOKEN environment variable is set; otherwise, or if the remote is
+// not on GitHub, tags are pushed without creating releases. GITHUB_API_URL may
+// be set to use a different API endpoint.
+func newPublisher(cfg *config.Config, local bool) (publish.Publisher, error) {
+	if local {
+		return nil, nil
+	}
+
+	remote := cfg.ReleaseRemote()
+	var owner, repo string
+	if cfg.Release != nil && cfg.Release.GitHubRepo != "" {
+		var ok bool
+		owner, repo, ok = strings.Cut(cfg.Release.GitHubRepo, "/")
+		if !ok {
+			return nil, fmt.Errorf("release.github_repo must be of the form OWNER/REPO, got %q", cfg.Release.GitHubRepo)
+		}
+	} else {
+		url, err := publish.RemoteURL(remote)
+		if err != nil {
+			return nil, err
+		}
+		owner, repo, err = publish.ParseGitHubRemote(url)
+		if err != nil {
+			fmt.Printf("Warning: %v; pushing tags without creating GitHub releases (set release.github_repo to create them)\n", err)
+			return &publish.Git{Remote: remote}, nil
+		}
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		fmt.Println("Warning: GITHUB_TOKEN is not set; pushing tags without creating GitHub releases")
+		return &publish.Git{Remote: remote}, nil
+	}
+	return &publish.GitHub{
+		Remote:  remote,
+		Owner:   owner,
+		Repo:    repo,
+		Token:   token,
+		BaseURL: os.Getenv("GITHUB_API_URL"),
+	}, nil
+}
+
+// recordRelease moves the prepared release into the release history and
+// makes it the current version. Release notes are not kept in the history;
+// they are recorded in CHANGES.md.
+func recordRelease(r *state.ReleaseState) {
+	released := *r.Prepared
+	released.Notes = ""
+	version, history := releaseLine(r, released.Branch)
+	*history = append(*history, released)
+	*version = released.Version
+	r.Prepared = nil
+}
+
+// previousVersion returns the version that the prepared release in r
+// follows.
+func previousVersion(r *state.ReleaseState) string {
+	version, _ := releaseLine(r, r.Prepared.Branch)
+	return *version
+}
+
+// isLastRelease reports whether prepared is the most recent release recorded
+// in r.
+func isLastRelease(r *state.ReleaseState, prepared *state.ReleaseInfo) bool {
+	_, history := releaseLine(r, prepared.Branch)
+	return lastReleaseTag(*history, false) == prepared.Tag
+}
+
+// checkTagAvailable returns an error if the tag already exists.
+func checkTagAvailable(tag string) error {
+	exists, err := release.TagExists(tag)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("tag %s already exists", tag)
+	}
+	return nil
+}
+
+// createGitTag creates the tag at commit. It does nothing if the tag already
+// points at commit, so that an interrupted release can be resumed.
+func createGitTag(tag, commit string) error {
+	done, err := tagAt(tag, commit)
+	if err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+	cmd := exec.Command("git", "tag", tag, commit)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w\n%s", tag, err, output)
+	}
+	return nil
+}
+
+// tagAt reports whether the tag exists and points at commit.
+func tagAt(tag, commit string) (bool, error) {
+	exists, err := release.TagExists(tag)
+	if err != nil || !exists {
+		return false, err
+	}
+	cmd := exec.Command("git", "rev-parse", "refs/tags/"+tag+"^{commit}", commit+"^{commit}")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve tag %s: %w\n%s", tag, err, output)
+	}
+	ids := strings.Fields(string(output))
+	return len(ids) == 2 && ids[0] == ids[1], nil
+}
+
+func deleteGitTag(tag string) error {
+	cmd := exec.Command("git", "tag", "--delete", tag)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete tag %s: %w\n%s", tag, err, output)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package librarian
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/journal"
+	"github.com/julieqiu/exp/librarian/internal/publish"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+func TestReleaseFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"pyproject.toml":            "[project]\nname = \"google-cloud-secret-manager\"\nreadme = \"README.rst\"\n",
+		"README.rst":                "",
+		"noxfile.py":                "",
+		"google/cloud/__init__.py":  "",
+		"tests/unit/test_client.py": "",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{Librarian: config.LibrarianConfig{Language: "python"}}
+
+	artifact := &state.Artifact{Config: &state.ConfigState{Exclude: []string{"tests", "*.py"}}}
+	shipped, excluded, err := releaseFiles(cfg, dir, artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"README.rst", "google/cloud/__init__.py", "pyproject.toml"}
+	if diff := cmp.Diff(want, shipped); diff != "" {
+		t.Errorf("releaseFiles() shipped mismatch (-want +got):\n%s", diff)
+	}
+	wantExcluded := []string{"noxfile.py", "tests/unit/test_client.py"}
+	if diff := cmp.Diff(wantExcluded, excluded); diff != "" {
+		t.Errorf("releaseFiles() excluded mismatch (-want +got):\n%s", diff)
+	}
+
+	artifact.Config.Exclude = []string{"build"}
+	if _, excluded, err := releaseFiles(cfg, dir, artifact); err != nil || len(excluded) != 0 {
+		t.Errorf("releaseFiles() = %v, %v, want no excluded files", excluded, err)
+	}
+
+	artifact.Config.Exclude = []string{"README.rst"}
+	_, _, err = releaseFiles(cfg, dir, artifact)
+	if err == nil || !strings.Contains(err.Error(), "excluded file README.rst is referenced by the package manifest") {
+		t.Errorf("releaseFiles() = %v, want referenced file error", err)
+	}
+}
+
+func TestRecordRelease(t *testing.T) {
+	r := &state.ReleaseState{
+		Version: "v2.1.0",
+		History: []state.ReleaseInfo{{Version: "v2.1.0", Tag: "v2.1.0", Branch: "main"}},
+		Branches: map[string]*state.BranchState{
+			"release/v1.x": {Version: "v1.4.0"},
+		},
+		Prepared: &state.ReleaseInfo{Version: "v1.4.1", Tag: "v1.4.1", Branch: "release/v1.x", Notes: "fix: backport"},
+	}
+	recordRelease(r)
+	want := &state.ReleaseState{
+		Version: "v2.1.0",
+		History: []state.ReleaseInfo{{Version: "v2.1.0", Tag: "v2.1.0", Branch: "main"}},
+		Branches: map[string]*state.BranchState{
+			"release/v1.x": {
+				Version: "v1.4.1",
+				History: []state.ReleaseInfo{{Version: "v1.4.1", Tag: "v1.4.1", Branch: "release/v1.x"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, r); diff != "" {
+		t.Errorf("recordRelease() mismatch (-want +got):\n%s", diff)
+	}
+
+	r.Prepared = &state.ReleaseInfo{Version: "v2.2.0", Tag: "v2.2.0", Branch: "main"}
+	recordRelease(r)
+	if r.Version != "v2.2.0" || len(r.History) != 2 || r.Branches["release/v1.x"].Version != "v1.4.1" {
+		t.Errorf("recordRelease() on main = %+v, want only the main version and history updated", r)
+	}
+}
+
+func TestCheckTagCollisions(t *testing.T) {
+	cfg := &config.Config{Release: &config.ReleaseConfig{TagFormat: "{id}-v{version}"}}
+	artifacts := map[string]*state.Artifact{
+		"a/storage": {Release: &state.ReleaseState{Version: "v1.0.0"}},
+		"b/storage": {Release: &state.ReleaseState{Version: "v2.0.0"}},
+		"pubsub":    {Release: &state.ReleaseState{Version: "v1.0.0"}},
+		"c/storage": {Generate: &state.GenerateState{}},
+	}
+	if err := checkTagCollisions(cfg, artifacts, []string{"pubsub"}); err != nil {
+		t.Errorf("checkTagCollisions(pubsub) = %v, want nil", err)
+	}
+	err := checkTagCollisions(cfg, artifacts, []string{"a/storage", "b/storage", "pubsub"})
+	if err == nil || !strings.Contains(err.Error(), "a/storage, b/storage share the tag storage-v0.0.0") {
+		t.Errorf("checkTagCollisions() = %v, want shared tag error", err)
+	}
+
+	cfg.Release.TagFormat = "{path}/v{version}"
+	if err := checkTagCollisions(cfg, artifacts, []string{"a/storage", "b/storage", "pubsub"}); err != nil {
+		t.Errorf("checkTagCollisions() with {path} = %v, want nil", err)
+	}
+}
+
+func TestNewPublisherWithoutToken(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	cfg := &config.Config{Release: &config.ReleaseConfig{GitHubRepo: "googleapis/google-cloud-go"}}
+	pub, err := newPublisher(cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pub.(*publish.Git); !ok {
+		t.Errorf("newPublisher() = %T, want *publish.Git", pub)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "token")
+	pub, err = newPublisher(cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pub.(*publish.GitHub); !ok {
+		t.Errorf("newPublisher() = %T, want *publish.GitHub", pub)
+	}
+}
+
+func TestDependencyUpdates(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFiles(t, dir, map[string]string{
+		"auth/go.mod":    "module cloud.google.com/go/auth\n",
+		"storage/go.mod": "module cloud.google.com/go/storage\n\nrequire cloud.google.com/go/auth v0.8.0\n\nreplace cloud.google.com/go/auth => ../auth\n",
+	})
+	artifacts := map[string]*state.Artifact{
+		"auth":    {Release: &state.ReleaseState{Version: "v0.9.0", Prepared: &state.ReleaseInfo{Version: "v0.10.0"}}},
+		"storage": {Release: &state.ReleaseState{Version: "v1.0.0"}},
+	}
+	graph, _, err := artifactOrder(artifacts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := dependencyUpdates(graph, artifacts, "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"update cloud.google.com/go/auth to v0.9.0",
+		"cloud.google.com/go/auth v0.10.0 is released alongside this version",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("dependencyUpdates() mismatch (-want +got):\n%s", diff)
+	}
+	// The requirement is raised to the released version, not the prepared
+	// one, which has no tag yet.
+	data, err := os.ReadFile(filepath.Join(dir, "storage", "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "require cloud.google.com/go/auth v0.9.0") {
+		t.Errorf("storage/go.mod does not require the released version:\n%s", data)
+	}
+
+	artifacts["auth"].Release.Prepared = nil
+	got, err = dependencyUpdates(graph, artifacts, "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("dependencyUpdates() = %v, want none for an up-to-date requirement", got)
+	}
+}
+
+// git runs git with args in the current directory and returns its output.
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestCheckResume(t *testing.T) {
+	j := &journal.Journal{Entries: []*journal.Entry{
+		{Path: "auth", Steps: []journal.Step{journal.StepTag, journal.StepPush, journal.StepRelease, journal.StepSave}},
+		{Path: "storage", Steps: []journal.Step{journal.StepTag}},
+	}}
+	for _, test := range []struct {
+		name    string
+		path    string
+		local   bool
+		wantErr bool
+	}{
+		{name: "all", path: ""},
+		{name: "path outside release", path: "pubsub", wantErr: true},
+		{name: "path in larger release", path: "storage", wantErr: true},
+		{name: "local with unpushed tags", local: true, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkResume(j, test.path, test.local)
+			if (err != nil) != test.wantErr {
+				t.Errorf("checkResume() error = %v, wantErr %t", err, test.wantErr)
+			}
+		})
+	}
+
+	single := &journal.Journal{Local: true, Entries: []*journal.Entry{{Path: "storage", Steps: []journal.Step{journal.StepTag}}}}
+	if err := checkResume(single, "./storage/", true); err != nil {
+		t.Errorf("checkResume() for the released path = %v, want nil", err)
+	}
+}
+
+func TestCreateGitTag(t *testing.T) {
+	chdir(t, t.TempDir())
+	git(t, "init", "--quiet")
+	git(t, "commit", "--quiet", "--allow-empty", "-m", "first")
+	first := git(t, "rev-parse", "HEAD")
+	git(t, "commit", "--quiet", "--allow-empty", "-m", "second")
+
+	if err := createGitTag("v1.0.0", first); err != nil {
+		t.Fatal(err)
+	}
+	// Resuming a release re-creates a tag that already points at the commit.
+	if err := createGitTag("v1.0.0", first[:7]); err != nil {
+		t.Errorf("createGitTag() for an existing tag at the commit = %v, want nil", err)
+	}
+	if err := createGitTag("v1.0.0", "HEAD"); err == nil {
+		t.Error("createGitTag() for an existing tag at another commit succeeded, want error")
+	}
+	if ok, err := tagAt("v1.0.0", first); err != nil || !ok {
+		t.Errorf("tagAt() = %t, %v, want true, nil", ok, err)
+	}
+	if ok, err := tagAt("v2.0.0", first); err != nil || ok {
+		t.Errorf("tagAt() for a missing tag = %t, %v, want false, nil", ok, err)
+	}
+}
+
+func TestReleaseDryRunResume(t *testing.T) {
+	chdir(t, t.TempDir())
+	git(t, "init", "--quiet")
+	writeFiles(t, ".", map[string]string{
+		".librarian/config.yaml":  "librarian:\n  version: v0.1.0\n  language: go\nrelease:\n  tag_format: '{id}/v{version}'\n",
+		"storage/.librarian.yaml": "release:\n  version: v1.0.0\n  prepared:\n    version: v1.1.0\n    tag: storage/v1.1.0\n",
+	})
+	git(t, "add", ".")
+	git(t, "commit", "--quiet", "-m", "prepare")
+	commit := git(t, "rev-parse", "HEAD")
+	git(t, "tag", "storage/v1.1.0")
+
+	// The tag was created by the interrupted release, so a dry run of the
+	// resumed release does not report it as a conflict.
+	j := &journal.Journal{Local: true, Entries: []*journal.Entry{{
+		Path:     "storage",
+		Prepared: state.ReleaseInfo{Version: "v1.1.0", Tag: "storage/v1.1.0", Commit: commit},
+		Steps:    []journal.Step{journal.StepTag},
+	}}}
+	if err := j.Save(); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"librarian", "release", "--dry-run", "./storage/"}
+	if err := NewApp().Run(context.Background(), args); err != nil {
+		t.Fatalf("Run(%q) = %v, want nil", args, err)
+	}
+	if _, err := os.Stat(journal.File); err != nil {
+		t.Errorf("dry run removed the journal: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package librarian
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/exp/librarian/internal/deps"
+	"github.com/julieqiu/exp/librarian/internal/release"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+// snippetsDir is the directory, relative to the repository root, holding the
+// generated Go snippets of each module, in a subdirectory named after the
+// module's directory.
+const snippetsDir = "internal/generated/snippets"
+
+// changelogFile is the changelog that release preparation maintains in each
+// artifact directory. It is not generated, so purging an artifact leaves it
+// in place unless a remove pattern covers it.
+const changelogFile = "CHANGELOG.md"
+
+// purgeArtifact removes the files and references that belong to the artifact
+// at path, before its state file is removed: the generated files, except those
+// matching a keep pattern; replace directives in other go.mod files that point
+// at path; and its snippets directory. It prints what was removed and the
+// release tags of the artifact, which are left in place. If dryRun is set, it
+// only prints what would be removed.
+func purgeArtifact(path string, artifact *state.Artifact, dryRun bool) error {
+	if artifact.Generate != nil {
+		var keep, remove []string
+		if artifact.Config != nil {
+			keep, remove = artifact.Config.Keep, artifact.Config.Remove
+		}
+		if dryRun {
+			files, _, err := generatedFiles(path, keep, remove)
+			if err != nil {
+				return fmt.Errorf("failed to list generated files: %w", err)
+			}
+			for _, f := range files {
+				fmt.Printf("[dry run] Would delete %s\n", f)
+			}
+		} else {
+			deleted, err := deleteGeneratedFiles(path, keep, remove)
+			if err != nil {
+				return fmt.Errorf("failed to delete generated files: %w", err)
+			}
+			fmt.Printf("Deleted %d generated files in %s\n", deleted, path)
+		}
+	} else {
+		fmt.Printf("%s is not generated by librarian; leaving its files in place\n", path)
+	}
+
+	modDirs, err := goModDirs(path)
+	if err != nil {
+		return err
+	}
+	for _, dir := range modDirs {
+		goMod := filepath.Join(dir, "go.mod")
+		if dryRun {
+			found, err := deps.HasLocalReplace(dir, path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", goMod, err)
+			}
+			if found {
+				fmt.Printf("[dry run] Would remove replace directive for %s from %s\n", path, goMod)
+			}
+			continue
+		}
+		changed, err := deps.RemoveLocalReplace(dir, path)
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", goMod, err)
+		}
+		if changed {
+			fmt.Printf("Removed replace directive for %s from %s\n", path, goMod)
+		}
+	}
+
+	snippets := filepath.Join(snippetsDir, filepath.Clean(path))
+	if info, err := os.Stat(snippets); err == nil && info.IsDir() {
+		if dryRun {
+			fmt.Printf("[dry run] Would delete snippets in %s\n", snippets)
+		} else {
+			if err := os.RemoveAll(snippets); err != nil {
+				return fmt.Errorf("failed to remove snippets: %w", err)
+			}
+			fmt.Printf("Deleted snippets in %s\n", snippets)
+		}
+	}
+
+	tags, err := releaseTags(artifact.Release)
+	if err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		fmt.Println("The following release tags were left in place; delete them by hand if they are no longer wanted:")
+		for _, tag := range tags {
+			fmt.Printf("  %s\n", tag)
+		}
+	}
+	return nil
+}
+
+// generatedFiles returns the files in dir that purging deletes, and the
+// directories walked to find them, in walk order. Files matching a keep
+// pattern, artifacts nested in dir, the state file, and the changelog are
+// left out; the changelog only if no remove pattern matches it.
+func generatedFiles(dir string, keep, remove []string) (files, dirs []string, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." {
+				if _, err := os.Stat(state.File(path)); err == nil {
+					// A nested artifact is not part of this one.
+					return filepath.SkipDir
+				}
+			}
+			dirs = append(dirs, path)
+			return nil
+		}
+		if rel == ".librarian.yaml" || matchAny(keep, rel) {
+			return nil
+		}
+		if rel == changelogFile && !matchAny(remove, rel) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, dirs, nil
+}
+
+// deleteGeneratedFiles deletes the files in dir listed by generatedFiles, and
+// then any directories left empty. It returns the number of files deleted.
+func deleteGeneratedFiles(dir string, keep, remove []string) (int, error) {
+	files, dirs, err := generatedFiles(dir, keep, remove)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return 0, err
+		}
+	}
+	// Remove directories deepest first, so that parents are empty when
+	// they are reached. The artifact directory keeps its state file until
+	// the artifact is removed.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if err != nil {
+			return 0, err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(dirs[i]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(files), nil
+}
+
+// goModDirs returns the directories of the repository, other than the
+// artifact directory exclude and its subdirectories, that contain a go.mod
+// file. Hidden directories are skipped.
+func goModDirs(exclude string) ([]string, error) {
+	exclude = filepath.Clean(exclude)
+	var dirs []string
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == exclude || (path != "." && strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find go.mod files: %w", err)
+	}
+	return dirs, nil
+}
+
+// releaseTags returns the tags of the releases recorded in r, including
+// those made from maintenance branches, that exist in the repository.
+func releaseTags(r *state.ReleaseState) ([]string, error) {
+	if r == nil {
+		return nil, nil
+	}
+	histories := [][]state.ReleaseInfo{r.History}
+	for _, b := range r.Branches {
+		histories = append(histories, b.History)
+	}
+	var tags []string
+	for _, history := range histories {
+		for _, info := range history {
+			if info.Tag == "" {
+				continue
+			}
+			exists, err := release.TagExists(info.Tag)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				tags = append(tags, info.Tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
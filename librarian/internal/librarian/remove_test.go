@@ -0,0 +1,112 @@
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeleteGeneratedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		".librarian.yaml",
+		"CHANGELOG.md",
+		"README.md",
+		"client.go",
+		"apiv1/client.go",
+		"apiv1/doc.go",
+		"internal/version.go",
+		"nested/.librarian.yaml",
+		"nested/nested.go",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := deleteGeneratedFiles(dir, []string{"README.md", "internal"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleteGeneratedFiles() = %d, want 3", deleted)
+	}
+	got, err := listFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		".librarian.yaml",
+		"CHANGELOG.md",
+		"README.md",
+		"internal",
+		"internal/version.go",
+		"nested",
+		"nested/.librarian.yaml",
+		"nested/nested.go",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("remaining files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeleteGeneratedFilesChangelog(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".librarian.yaml": "",
+		"CHANGELOG.md":    "",
+		"apiv1/client.go": "",
+	})
+	// A remove pattern covering the changelog lets it be deleted.
+	deleted, err := deleteGeneratedFiles(dir, nil, []string{"CHANGELOG.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleteGeneratedFiles() = %d, want 2", deleted)
+	}
+	got, err := listFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{".librarian.yaml"}, got); diff != "" {
+		t.Errorf("remaining files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRemovePurgeConfirmation(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeFiles(t, "storage", map[string]string{
+		".librarian.yaml": "generate:\n  apis:\n    - path: google/storage/v2\n",
+		"client.go":       "package storage\n",
+	})
+	want := map[string]string{
+		"storage/.librarian.yaml": "generate:\n  apis:\n    - path: google/storage/v2\n",
+		"storage/client.go":       "package storage\n",
+	}
+	err := NewApp().Run(context.Background(), []string{"librarian", "remove", "--purge", "storage"})
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("remove --purge without --yes = %v, want error", err)
+	}
+	if err := NewApp().Run(context.Background(), []string{"librarian", "remove", "--purge", "--dry-run", "storage"}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, readFiles(t, ".")); diff != "" {
+		t.Errorf("files changed without --yes (-want +got):\n%s", diff)
+	}
+
+	if err := NewApp().Run(context.Background(), []string{"librarian", "remove", "--purge", "--yes", "storage"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFiles(t, "."); len(got) != 0 {
+		t.Errorf("remove --purge --yes left %v, want no files", got)
+	}
+}
@@ -0,0 +1,44 @@
+package librarian
+
+import (
+	"context"
+
+	gogenerator "github.com/julieqiu/exp/librarian/container/go"
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/container"
+)
+
+// localHandlers are the generators that the local runtime can run in-process
+// when no generator executable is configured, keyed by language.
+//
+// Only the generate command is supported in-process. The configure,
+// release-stage, and build commands of the container contract require a
+// generator executable (--generator) or a container runtime.
+var localHandlers = map[string]map[string]container.Handler{
+	"go": {"generate": goGenerate},
+}
+
+// newRuntime returns the runtime used to run generators. name and generator
+// come from the --runtime and --generator flags; if empty, generate.runtime
+// and generate.generator in cfg are used.
+func newRuntime(cfg *config.Config, name, generator string) (container.Runtime, error) {
+	if cfg.Generate != nil {
+		if name == "" {
+			name = cfg.Generate.Runtime
+		}
+		if generator == "" {
+			generator = cfg.Generate.Generator
+		}
+	}
+	return container.NewRuntime(name, generator, localHandlers[cfg.Librarian.Language])
+}
+
+// goGenerate runs the Go generator in-process.
+func goGenerate(ctx context.Context, dirs map[string]string) error {
+	return gogenerator.Generate(ctx, &gogenerator.Config{
+		LibrarianDir: dirs["librarian"],
+		InputDir:     dirs["input"],
+		OutputDir:    dirs["output"],
+		SourceDir:    dirs["source"],
+	})
+}
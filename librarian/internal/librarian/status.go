@@ -0,0 +1,178 @@
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/release"
+	"github.com/julieqiu/exp/librarian/internal/state"
+	"github.com/urfave/cli/v3"
+)
+
+// artifactStatus summarizes the state of an artifact.
+type artifactStatus struct {
+	Path     string `json:"path"`
+	Version  string `json:"version,omitempty"`
+	Prepared string `json:"prepared,omitempty"`
+
+	// Generated reports whether the artifact is generated by librarian.
+	Generated bool `json:"generated"`
+
+	// StalePins lists the generation pins that differ from
+	// .librarian/config.yaml.
+	StalePins []stalePin `json:"stale_pins,omitempty"`
+
+	// UnreleasedCommits is the number of commits touching the artifact
+	// since its last release tag. It is nil for artifacts that are not
+	// released by librarian.
+	UnreleasedCommits *int `json:"unreleased_commits,omitempty"`
+
+	// UnreleasedError explains why the unreleased commits could not be
+	// counted, for example because the last release tag is missing from a
+	// shallow clone.
+	UnreleasedError string `json:"unreleased_error,omitempty"`
+}
+
+// stalePin is a generation pin recorded in an artifact's state that differs
+// from the repository configuration.
+type stalePin struct {
+	Name     string `json:"name"` // "googleapis", "container", or "librarian"
+	Artifact string `json:"artifact"`
+	Config   string `json:"config"`
+}
+
+func statusCommand(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	artifacts, err := state.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load artifacts: %w", err)
+	}
+
+	var paths []string
+	for path := range artifacts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	statuses := []*artifactStatus{}
+	for _, path := range paths {
+		s, err := newArtifactStatus(cfg, path, artifacts[path])
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, s)
+	}
+
+	if cmd.Bool("json") {
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal status: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(statuses) == 0 {
+		fmt.Println("No artifacts are tracked by librarian.")
+		return nil
+	}
+	return writeStatusTable(os.Stdout, statuses)
+}
+
+// newArtifactStatus returns the status of the artifact at path.
+func newArtifactStatus(cfg *config.Config, path string, artifact *state.Artifact) (*artifactStatus, error) {
+	s := &artifactStatus{
+		Path:      path,
+		Generated: artifact.Generate != nil,
+		StalePins: stalePins(cfg, artifact.Generate),
+	}
+	if r := artifact.Release; r != nil {
+		s.Version = r.Version
+		if r.Prepared != nil {
+			s.Prepared = r.Prepared.Version
+		}
+		commits, err := release.CommitsSince(lastReleaseTag(r.History, false), path)
+		if err != nil {
+			s.UnreleasedError = err.Error()
+			return s, nil
+		}
+		n := len(commits)
+		s.UnreleasedCommits = &n
+	}
+	return s, nil
+}
+
+// stalePins returns the pins in gen that differ from cfg. It returns nil if
+// gen is nil.
+func stalePins(cfg *config.Config, gen *state.GenerateState) []stalePin {
+	if gen == nil {
+		return nil
+	}
+	var pins []stalePin
+	add := func(name, artifact, want string) {
+		if artifact != want {
+			pins = append(pins, stalePin{Name: name, Artifact: artifact, Config: want})
+		}
+	}
+	var googleapis, container string
+	if cfg.Generate != nil {
+		if cfg.Generate.Googleapis != nil {
+			googleapis = cfg.Generate.Googleapis.Ref
+		}
+		container = cfg.ContainerImage()
+	}
+	add("googleapis", gen.Googleapis.Ref, googleapis)
+	artifactContainer := gen.Container
+	if cfg.Generate == nil || cfg.Generate.Container == nil || cfg.Generate.Container.Digest == "" {
+		// Without a pinned digest, the digest recorded by generate is not
+		// a pin to compare.
+		artifactContainer.Digest = ""
+	}
+	add("container", containerImage(artifactContainer), container)
+	add("librarian", gen.Librarian, cfg.Librarian.Version)
+	return pins
+}
+
+// writeStatusTable writes statuses as a table.
+func writeStatusTable(w io.Writer, statuses []*artifactStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tVERSION\tPREPARED\tPINS\tUNRELEASED")
+	for _, s := range statuses {
+		pins := "-"
+		if s.Generated {
+			pins = "current"
+		}
+		if len(s.StalePins) > 0 {
+			var names []string
+			for _, p := range s.StalePins {
+				names = append(names, p.Name)
+			}
+			pins = "stale: " + strings.Join(names, ", ")
+		}
+		unreleased := "-"
+		if s.UnreleasedCommits != nil {
+			unreleased = strconv.Itoa(*s.UnreleasedCommits)
+		} else if s.UnreleasedError != "" {
+			unreleased = "unknown"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Path, orDash(s.Version), orDash(s.Prepared), pins, unreleased)
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
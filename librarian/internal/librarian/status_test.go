@@ -0,0 +1,105 @@
+package librarian
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+func TestStalePins(t *testing.T) {
+	cfg := &config.Config{
+		Librarian: config.LibrarianConfig{Version: "v0.5.0"},
+		Generate: &config.GenerateConfig{
+			Container:  &config.ContainerConfig{Image: "go-generator", Tag: "v2"},
+			Googleapis: &config.RepoConfig{Repo: "github.com/googleapis/googleapis", Ref: "def456"},
+		},
+	}
+	gen := &state.GenerateState{
+		Librarian:  "v0.5.0",
+		Container:  state.ContainerState{Image: "go-generator", Tag: "v1"},
+		Googleapis: state.GoogleapisState{Repo: "github.com/googleapis/googleapis", Ref: "abc123"},
+	}
+	got := stalePins(cfg, gen)
+	want := []stalePin{
+		{Name: "googleapis", Artifact: "abc123", Config: "def456"},
+		{Name: "container", Artifact: "go-generator:v1", Config: "go-generator:v2"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("stalePins() mismatch (-want +got):\n%s", diff)
+	}
+	if got := stalePins(cfg, nil); got != nil {
+		t.Errorf("stalePins(nil) = %v, want nil", got)
+	}
+}
+
+func TestWriteStatusTable(t *testing.T) {
+	three := 3
+	zero := 0
+	statuses := []*artifactStatus{
+		{
+			Path:              "secretmanager",
+			Version:           "v1.2.0",
+			Prepared:          "v1.3.0",
+			Generated:         true,
+			StalePins:         []stalePin{{Name: "googleapis"}, {Name: "container"}},
+			UnreleasedCommits: &three,
+		},
+		{Path: "storage", Version: "v2.0.0", Generated: true, UnreleasedCommits: &zero},
+		{Path: "tools"},
+		{Path: "vision", Version: "v1.0.0", UnreleasedError: "failed to read git log for vision: exit status 128"},
+	}
+	var buf bytes.Buffer
+	if err := writeStatusTable(&buf, statuses); err != nil {
+		t.Fatal(err)
+	}
+	want := `PATH           VERSION  PREPARED  PINS                          UNRELEASED
+secretmanager  v1.2.0   v1.3.0    stale: googleapis, container  3
+storage        v2.0.0   -         current                       0
+tools          -        -         -                             -
+vision         v1.0.0   -         -                             unknown
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("writeStatusTable() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewArtifactStatusUnreleased(t *testing.T) {
+	chdir(t, t.TempDir())
+	git(t, "init", "--quiet")
+	writeFiles(t, ".", map[string]string{"storage/storage.go": "package storage\n"})
+	git(t, "add", "-A")
+	git(t, "commit", "--quiet", "-m", "feat: add storage")
+	git(t, "tag", "storage/v1.0.0")
+	writeFiles(t, ".", map[string]string{"storage/.librarian.yaml": "release:\n  version: v1.0.0\n"})
+	git(t, "add", "-A")
+	git(t, "commit", "--quiet", "-m", "chore: record storage release")
+	writeFiles(t, ".", map[string]string{"storage/storage.go": "package storage // fixed\n"})
+	git(t, "add", "-A")
+	git(t, "commit", "--quiet", "-m", "fix: fix storage")
+
+	released := func(tag string) *state.Artifact {
+		return &state.Artifact{Release: &state.ReleaseState{
+			Version: "v1.0.0",
+			History: []state.ReleaseInfo{{Version: "v1.0.0", Tag: tag}},
+		}}
+	}
+	s, err := newArtifactStatus(&config.Config{}, "storage", released("storage/v1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UnreleasedCommits == nil || *s.UnreleasedCommits != 1 {
+		t.Errorf("UnreleasedCommits = %v, want 1 (commits only touching .librarian.yaml are not counted)", s.UnreleasedCommits)
+	}
+
+	// A missing tag, as in a shallow clone, makes the count unknown.
+	s, err = newArtifactStatus(&config.Config{}, "storage", released("storage/v0.9.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UnreleasedCommits != nil || s.UnreleasedError == "" {
+		t.Errorf("newArtifactStatus() = %+v, want an unknown unreleased count", s)
+	}
+}
@@ -0,0 +1,293 @@
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/release"
+	"github.com/julieqiu/exp/librarian/internal/state"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// diagnostic is a problem found in a librarian state file.
+type diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (d diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+func validateCommand(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	artifacts, err := state.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load artifacts: %w", err)
+	}
+
+	v := &validator{cfg: cfg, tagExists: release.TagExists}
+	diags, err := v.validate(artifacts)
+	if err != nil {
+		return err
+	}
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	if len(diags) > 0 {
+		return fmt.Errorf("found %d problem(s) in %d artifact(s)", len(diags), countFiles(diags))
+	}
+	fmt.Printf("Validated %d artifact(s); no problems found.\n", len(artifacts))
+	return nil
+}
+
+func countFiles(diags []diagnostic) int {
+	files := make(map[string]bool)
+	for _, d := range diags {
+		files[d.File] = true
+	}
+	return len(files)
+}
+
+// validator checks artifact state files for consistency with each other, the
+// repository configuration, and the git repository.
+type validator struct {
+	cfg *config.Config
+
+	// tagExists reports whether a git tag exists.
+	tagExists func(tag string) (bool, error)
+}
+
+// validate returns the problems found in artifacts, ordered by file and line.
+func (v *validator) validate(artifacts map[string]*state.Artifact) ([]diagnostic, error) {
+	var paths []string
+	for path := range artifacts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var diags []diagnostic
+	modules := make(map[string]string) // Go module -> first artifact path
+	for _, path := range paths {
+		artifact := artifacts[path]
+		file := filepath.Join(path, ".librarian.yaml")
+		doc, err := readYAMLNode(file)
+		if err != nil {
+			return nil, err
+		}
+		report := func(msg string, keys ...any) {
+			diags = append(diags, diagnostic{File: file, Line: yamlLine(doc, keys...), Message: msg})
+		}
+
+		if artifact.Release != nil && v.cfg.Release == nil {
+			report("release section is set, but .librarian/config.yaml has no release section", "release")
+		}
+
+		if c := artifact.Config; c != nil {
+			files, err := listFiles(path)
+			if err != nil {
+				return nil, err
+			}
+			// Remove patterns are not checked: they name generator output
+			// that is deleted after generation, so a clean tree has no
+			// matching files.
+			for i, pattern := range c.Keep {
+				if !matchesAnyFile(pattern, files) {
+					report(fmt.Sprintf("keep pattern %q matches no files", pattern), "config", "keep", i)
+				}
+			}
+		}
+
+		if l := artifact.Language; l != nil && l.Go != nil && l.Go.Module != "" {
+			if other, ok := modules[l.Go.Module]; ok {
+				report(fmt.Sprintf("go module %s is also used by %s", l.Go.Module, other), "language", "go", "module")
+			} else {
+				modules[l.Go.Module] = path
+			}
+		}
+
+		if r := artifact.Release; r != nil {
+			problems, err := v.validateRelease(r, versionScheme(artifact))
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range problems {
+				report(p.msg, p.keys...)
+			}
+		}
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		return diags[i].Line < diags[j].Line
+	})
+	return diags, nil
+}
+
+// releaseProblem is a problem in a release section, located by its YAML keys
+// relative to the document root.
+type releaseProblem struct {
+	msg  string
+	keys []any
+}
+
+func (v *validator) validateRelease(r *state.ReleaseState, scheme release.Scheme) ([]releaseProblem, error) {
+	var problems []releaseProblem
+	if release.Released(r.Version) {
+		if _, err := release.ParseVersionScheme(r.Version, scheme); err != nil {
+			problems = append(problems, releaseProblem{fmt.Sprintf("invalid version: %v", err), []any{"release", "version"}})
+		}
+	}
+	var branches []string
+	for name := range r.Branches {
+		branches = append(branches, name)
+	}
+	sort.Strings(branches)
+	for _, name := range branches {
+		if b := r.Branches[name]; release.Released(b.Version) {
+			if _, err := release.ParseVersionScheme(b.Version, scheme); err != nil {
+				problems = append(problems, releaseProblem{fmt.Sprintf("invalid version: %v", err), []any{"release", "branches", name, "version"}})
+			}
+		}
+	}
+	if p := r.Prepared; p != nil {
+		// A release prepared on a maintenance branch follows the branch's
+		// version, not the main line's.
+		version, _ := releaseLine(r, p.Branch)
+		prepared, err := release.ParseVersionScheme(p.Version, scheme)
+		if err != nil {
+			problems = append(problems, releaseProblem{fmt.Sprintf("invalid prepared version: %v", err), []any{"release", "prepared", "version"}})
+		} else if release.Released(*version) {
+			current, err := release.ParseVersionScheme(*version, scheme)
+			if err == nil && prepared.Compare(current) <= 0 {
+				problems = append(problems, releaseProblem{
+					fmt.Sprintf("prepared version %s is not greater than current version %s", p.Version, *version),
+					[]any{"release", "prepared", "version"},
+				})
+			}
+		}
+	}
+
+	checkHistory := func(history []state.ReleaseInfo, keys ...any) error {
+		for i, h := range history {
+			if h.Tag == "" {
+				continue
+			}
+			exists, err := v.tagExists(h.Tag)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				problems = append(problems, releaseProblem{fmt.Sprintf("history tag %s does not exist", h.Tag), append(slices.Clone(keys), "history", i, "tag")})
+			}
+		}
+		return nil
+	}
+	if err := checkHistory(r.History, "release"); err != nil {
+		return nil, err
+	}
+	for _, name := range branches {
+		if err := checkHistory(r.Branches[name].History, "release", "branches", name); err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// listFiles returns the paths of the files and directories under dir,
+// relative to dir and using forward slashes.
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+func matchesAnyFile(pattern string, files []string) bool {
+	for _, f := range files {
+		if matchPath(pattern, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// readYAMLNode parses the YAML file at path into a node tree.
+func readYAMLNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// yamlLine returns the line of the node reached by following keys from the
+// document root. Each key is a mapping key (string) or sequence index (int).
+// If a key is not found, the line of the deepest node found is returned.
+func yamlLine(doc *yaml.Node, keys ...any) int {
+	n := doc
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	line := n.Line
+	for _, key := range keys {
+		var next *yaml.Node
+		switch k := key.(type) {
+		case string:
+			if n.Kind != yaml.MappingNode {
+				return line
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == k {
+					line = n.Content[i].Line
+					next = n.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if n.Kind == yaml.SequenceNode && k < len(n.Content) {
+				next = n.Content[k]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			return line
+		}
+		n = next
+	}
+	return line
+}
@@ -0,0 +1,132 @@
+package librarian
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/julieqiu/exp/librarian/internal/config"
+	"github.com/julieqiu/exp/librarian/internal/state"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, "secretmanager"), map[string]string{
+		".librarian.yaml": `language:
+  go:
+    module: cloud.google.com/go/secretmanager
+config:
+  keep:
+    - CHANGES.md
+    - internal/version.go
+  remove:
+    - "*.tmp"
+release:
+  version: v1.3.0
+  prepared:
+    version: v1.3.0
+  history:
+    - version: v1.2.0
+      tag: secretmanager/v1.2.0
+    - version: v1.3.0
+      tag: secretmanager/v1.3.0
+`,
+		"CHANGES.md": "# Changes\n",
+	})
+	writeFiles(t, filepath.Join(dir, "storage"), map[string]string{
+		".librarian.yaml": `language:
+  go:
+    module: cloud.google.com/go/secretmanager
+release:
+  version: v2.0.0
+  prepared:
+    version: v2.1.0
+`,
+	})
+
+	writeFiles(t, filepath.Join(dir, "pubsub"), map[string]string{
+		".librarian.yaml": `release:
+  version: v2.0.0
+  prepared:
+    version: v1.2.5
+    branch: release/v1.x
+  branches:
+    release/v1.x:
+      version: v1.2.4
+      history:
+        - version: v1.2.4
+          tag: pubsub/v1.2.4
+`,
+	})
+
+	artifacts := make(map[string]*state.Artifact)
+	for _, name := range []string{"secretmanager", "storage", "pubsub"} {
+		path := filepath.Join(dir, name)
+		a, err := state.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		artifacts[path] = a
+	}
+
+	v := &validator{
+		cfg: &config.Config{},
+		tagExists: func(tag string) (bool, error) {
+			return tag == "secretmanager/v1.2.0", nil
+		},
+	}
+	got, err := v.validate(artifacts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretmanager := filepath.Join(dir, "secretmanager", ".librarian.yaml")
+	storage := filepath.Join(dir, "storage", ".librarian.yaml")
+	pubsub := filepath.Join(dir, "pubsub", ".librarian.yaml")
+	want := []diagnostic{
+		{pubsub, 1, "release section is set, but .librarian/config.yaml has no release section"},
+		{pubsub, 11, "history tag pubsub/v1.2.4 does not exist"},
+		{secretmanager, 7, `keep pattern "internal/version.go" matches no files`},
+		{secretmanager, 10, "release section is set, but .librarian/config.yaml has no release section"},
+		{secretmanager, 13, "prepared version v1.3.0 is not greater than current version v1.3.0"},
+		{secretmanager, 18, "history tag secretmanager/v1.3.0 does not exist"},
+		{storage, 3, "go module cloud.google.com/go/secretmanager is also used by " + filepath.Join(dir, "secretmanager")},
+		{storage, 4, "release section is set, but .librarian/config.yaml has no release section"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("validate() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidateNoProblems(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secretmanager")
+	writeFiles(t, path, map[string]string{
+		".librarian.yaml": "config:\n  keep:\n    - docs\nrelease:\n  version: 1.2.0\n  prepared:\n    version: 1.3.0rc1\n",
+		"docs/index.md":   "# Docs\n",
+	})
+	a, err := state.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Language = &state.LanguageState{Python: &state.PythonLanguage{Package: "google-cloud-secret-manager"}}
+
+	v := &validator{
+		cfg:       &config.Config{Release: &config.ReleaseConfig{}},
+		tagExists: func(string) (bool, error) { return true, nil },
+	}
+	got, err := v.validate(map[string]*state.Artifact{path: a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("validate() = %v, want no problems", got)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	d := diagnostic{File: "a/.librarian.yaml", Line: 3, Message: "bad"}
+	if got, want := d.String(), "a/.librarian.yaml:3: bad"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
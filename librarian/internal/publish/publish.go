@@ -0,0 +1,185 @@
+// Package publish pushes release tags and creates hosted releases.
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Release describes a hosted release for a tag.
+type Release struct {
+	Tag        string
+	Name       string
+	Notes      string
+	Prerelease bool
+}
+
+// Publisher publishes releases that have been tagged locally.
+type Publisher interface {
+	// PushTag pushes a local tag to the remote repository.
+	PushTag(ctx context.Context, tag string) error
+
+	// CreateRelease creates a hosted release for a pushed tag.
+	CreateRelease(ctx context.Context, r *Release) error
+}
+
+// DefaultGitHubAPI is the GitHub REST API endpoint.
+const DefaultGitHubAPI = "https://api.github.com"
+
+// GitHub publishes releases to a GitHub repository. Tags are pushed with git
+// and releases are created through the GitHub REST API.
+type GitHub struct {
+	// Remote is the git remote that tags are pushed to.
+	Remote string
+
+	// Owner and Repo identify the GitHub repository.
+	Owner string
+	Repo  string
+
+	// Token authenticates requests to the GitHub API.
+	Token string
+
+	// BaseURL is the GitHub API endpoint. Defaults to DefaultGitHubAPI.
+	BaseURL string
+
+	// Client is the HTTP client used for API requests. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// PushTag pushes the tag to g.Remote.
+func (g *GitHub) PushTag(ctx context.Context, tag string) error {
+	return pushTag(ctx, g.Remote, tag)
+}
+
+// CreateRelease creates a GitHub release for r.Tag. A release that already
+// exists for the tag, for example one created by an earlier attempt, is
+// treated as success.
+func (g *GitHub) CreateRelease(ctx context.Context, r *Release) error {
+	body, err := json.Marshal(struct {
+		TagName    string `json:"tag_name"`
+		Name       string `json:"name"`
+		Body       string `json:"body"`
+		Prerelease bool   `json:"prerelease"`
+	}{
+		TagName:    r.Tag,
+		Name:       r.Name,
+		Body:       r.Notes,
+		Prerelease: r.Prerelease,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal release: %w", err)
+	}
+
+	baseURL := g.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultGitHubAPI
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/releases", strings.TrimSuffix(baseURL, "/"), g.Owner, g.Repo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to create release %s: %w", r.Tag, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusUnprocessableEntity && bytes.Contains(msg, []byte(`"already_exists"`)) {
+			return nil
+		}
+		return fmt.Errorf("failed to create release %s: %s: %s", r.Tag, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Git publishes releases by pushing their tags to a remote, without creating
+// hosted releases.
+type Git struct {
+	// Remote is the git remote that tags are pushed to.
+	Remote string
+}
+
+// PushTag pushes the tag to g.Remote.
+func (g *Git) PushTag(ctx context.Context, tag string) error {
+	return pushTag(ctx, g.Remote, tag)
+}
+
+// CreateRelease does nothing; the pushed tag is the release.
+func (g *Git) CreateRelease(ctx context.Context, r *Release) error {
+	return nil
+}
+
+// pushTag pushes the tag to remote.
+func pushTag(ctx context.Context, remote, tag string) error {
+	cmd := exec.CommandContext(ctx, "git", "push", remote, "refs/tags/"+tag)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push tag %s to %s: %w\n%s", tag, remote, err, output)
+	}
+	return nil
+}
+
+// DryRun prints the operations a publisher would perform without performing
+// them.
+type DryRun struct {
+	Out io.Writer
+}
+
+// PushTag prints the push that would be performed.
+func (d *DryRun) PushTag(ctx context.Context, tag string) error {
+	fmt.Fprintf(d.Out, "  [dry run] Would push tag %s\n", tag)
+	return nil
+}
+
+// CreateRelease prints the release that would be created.
+func (d *DryRun) CreateRelease(ctx context.Context, r *Release) error {
+	kind := "release"
+	if r.Prerelease {
+		kind = "prerelease"
+	}
+	fmt.Fprintf(d.Out, "  [dry run] Would create GitHub %s %q for tag %s\n", kind, r.Name, r.Tag)
+	return nil
+}
+
+var githubRemoteRegex = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// ParseGitHubRemote returns the owner and repository name from a GitHub remote
+// URL, such as https://github.com/googleapis/google-cloud-go.git or
+// git@github.com:googleapis/google-cloud-go.git.
+func ParseGitHubRemote(url string) (owner, repo string, err error) {
+	m := githubRemoteRegex.FindStringSubmatch(strings.TrimSpace(url))
+	if m == nil {
+		return "", "", fmt.Errorf("not a GitHub remote: %s", url)
+	}
+	return m[1], m[2], nil
+}
+
+// RemoteURL returns the URL of the named git remote.
+func RemoteURL(remote string) (string, error) {
+	cmd := exec.Command("git", "remote", "get-url", remote)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get URL of remote %s: %w", remote, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,113 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGitHubCreateRelease(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/googleapis/google-cloud-go/releases" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q, want %q", auth, "Bearer secret")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	g := &GitHub{Owner: "googleapis", Repo: "google-cloud-go", Token: "secret", BaseURL: srv.URL}
+	err := g.CreateRelease(context.Background(), &Release{
+		Tag:        "secretmanager/v1.3.0-rc.1",
+		Name:       "secretmanager/v1.3.0-rc.1",
+		Notes:      "### Features\n\n* add GetSecret API",
+		Prerelease: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"tag_name":   "secretmanager/v1.3.0-rc.1",
+		"name":       "secretmanager/v1.3.0-rc.1",
+		"body":       "### Features\n\n* add GetSecret API",
+		"prerelease": true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("request body mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGitHubCreateReleaseError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Validation Failed"}`, http.StatusUnprocessableEntity)
+	}))
+	defer srv.Close()
+
+	g := &GitHub{Owner: "o", Repo: "r", BaseURL: srv.URL}
+	if err := g.CreateRelease(context.Background(), &Release{Tag: "v1.0.0"}); err == nil {
+		t.Error("CreateRelease() succeeded, want error")
+	}
+}
+
+func TestGitHubCreateReleaseAlreadyExists(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Validation Failed","errors":[{"resource":"Release","code":"already_exists","field":"tag_name"}]}`, http.StatusUnprocessableEntity)
+	}))
+	defer srv.Close()
+
+	g := &GitHub{Owner: "o", Repo: "r", BaseURL: srv.URL}
+	if err := g.CreateRelease(context.Background(), &Release{Tag: "v1.0.0"}); err != nil {
+		t.Errorf("CreateRelease() = %v, want nil for an existing release", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var out bytes.Buffer
+	d := &DryRun{Out: &out}
+	if err := d.PushTag(context.Background(), "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateRelease(context.Background(), &Release{Tag: "v1.0.0", Name: "v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "  [dry run] Would push tag v1.0.0\n  [dry run] Would create GitHub release \"v1.0.0\" for tag v1.0.0\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseGitHubRemote(t *testing.T) {
+	for _, test := range []struct {
+		url       string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{url: "https://github.com/googleapis/google-cloud-go.git", wantOwner: "googleapis", wantRepo: "google-cloud-go"},
+		{url: "https://github.com/googleapis/google-cloud-go", wantOwner: "googleapis", wantRepo: "google-cloud-go"},
+		{url: "git@github.com:googleapis/google-cloud-python.git", wantOwner: "googleapis", wantRepo: "google-cloud-python"},
+		{url: "https://gitlab.com/foo/bar.git", wantErr: true},
+	} {
+		t.Run(test.url, func(t *testing.T) {
+			owner, repo, err := ParseGitHubRemote(test.url)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseGitHubRemote() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if owner != test.wantOwner || repo != test.wantRepo {
+				t.Errorf("ParseGitHubRemote() = %q, %q, want %q, %q", owner, repo, test.wantOwner, test.wantRepo)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Package registry looks up container images in an OCI registry using the
+// registry v2 HTTP API.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/julieqiu/exp/librarian/internal/release"
+)
+
+// dockerHub is the registry host of images without a registry host, such
+// as "golang".
+const dockerHub = "registry-1.docker.io"
+
+// manifestTypes are the manifest media types accepted when resolving a
+// digest. Multi-platform indexes are preferred, so that the digest is the one
+// shown by `docker pull`.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Client looks up images in OCI registries. Registries that require a token
+// for anonymous pulls, as most public registries do, are supported.
+type Client struct {
+	// Client is the HTTP client used for requests. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// Reference is an image name split into its registry host and repository,
+// such as "us-central1-docker.pkg.dev" and
+// "cloud-sdk-librarian-prod/images-prod/python-librarian-generator".
+type Reference struct {
+	Host       string
+	Repository string
+}
+
+// ParseReference parses an image name without a tag or digest. Names
+// without a registry host refer to Docker Hub.
+func ParseReference(image string) (Reference, error) {
+	if image == "" || strings.ContainsAny(image, "@ ") {
+		return Reference{}, fmt.Errorf("invalid image name %q", image)
+	}
+	host, repo, ok := strings.Cut(image, "/")
+	if !ok || !strings.ContainsAny(host, ".:") && host != "localhost" {
+		host, repo = dockerHub, image
+		if !strings.Contains(repo, "/") {
+			repo = "library/" + repo
+		}
+	}
+	if last := repo[strings.LastIndex(repo, "/")+1:]; strings.Contains(last, ":") {
+		return Reference{}, fmt.Errorf("invalid image name %q: remove the tag", image)
+	}
+	return Reference{Host: host, Repository: repo}, nil
+}
+
+// baseURL returns the URL of the registry API. Registries on the local host
+// are accessed over plain HTTP, as Docker does.
+func (r Reference) baseURL() string {
+	scheme := "https"
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host + "/v2/" + r.Repository
+}
+
+// Tags returns the tags of image.
+func (c *Client) Tags(ctx context.Context, image string) ([]string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	next := ref.baseURL() + "/tags/list"
+	for next != "" {
+		resp, err := c.get(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", image, err)
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", image, err)
+		}
+		tags = append(tags, list.Tags...)
+		if next, err = nextPage(resp); err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", image, err)
+		}
+	}
+	return tags, nil
+}
+
+// nextPage returns the URL of the next page of a paginated response, from
+// its Link header.
+func nextPage(resp *http.Response) (string, error) {
+	link := resp.Header.Get("Link")
+	if link == "" || !strings.Contains(link, `rel="next"`) {
+		return "", nil
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("invalid Link header %q", link)
+	}
+	u, err := resp.Request.URL.Parse(link[start+1 : end])
+	if err != nil {
+		return "", fmt.Errorf("invalid Link header %q: %w", link, err)
+	}
+	return u.String(), nil
+}
+
+// Digest returns the digest of the manifest that tag of image refers to,
+// such as "sha256:3f1e...".
+func (c *Client) Digest(ctx context.Context, image, tag string) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{"Accept": {strings.Join(manifestTypes, ", ")}}
+	u := ref.baseURL() + "/manifests/" + url.PathEscape(tag)
+	resp, err := c.get(ctx, http.MethodHead, u, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s:%s: %w", image, tag, err)
+	}
+	resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// The digest header is optional, so fall back to hashing the manifest.
+	resp, err = c.get(ctx, http.MethodGet, u, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s:%s: %w", image, tag, err)
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to resolve %s:%s: %w", image, tag, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Latest returns the newest tag of image and its digest. The newest tag is
+// the highest stable semantic version, such as v1.4.0; if image has no such
+// tags, it is "latest".
+func (c *Client) Latest(ctx context.Context, image string) (tag, digest string, err error) {
+	tags, err := c.Tags(ctx, image)
+	if err != nil {
+		return "", "", err
+	}
+	tag = LatestTag(tags)
+	if tag == "" {
+		return "", "", fmt.Errorf("no version tags found for %s", image)
+	}
+	digest, err = c.Digest(ctx, image, tag)
+	if err != nil {
+		return "", "", err
+	}
+	return tag, digest, nil
+}
+
+// LatestTag returns the highest stable semantic version in tags, or "latest"
+// if there is none and tags contains it. Otherwise it returns an empty
+// string.
+func LatestTag(tags []string) string {
+	var latest string
+	var latestVersion release.Version
+	for _, tag := range tags {
+		v, err := release.ParseSemVer(tag)
+		if err != nil || v.IsPrerelease() {
+			continue
+		}
+		if latest == "" || v.Compare(latestVersion) > 0 {
+			latest, latestVersion = tag, v
+		}
+	}
+	if latest != "" {
+		return latest
+	}
+	for _, tag := range tags {
+		if tag == "latest" {
+			return tag
+		}
+	}
+	return ""
+}
+
+// get sends a request, authenticating with an anonymous bearer token if the
+// registry asks for one. Responses other than 200 OK are returned as errors.
+func (c *Client) get(ctx context.Context, method, u string, header http.Header) (*http.Response, error) {
+	resp, err := c.do(ctx, method, u, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := c.token(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+		header = header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Authorization", "Bearer "+token)
+		if resp, err = c.do(ctx, method, u, header); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", method, u, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, u string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// challengeParamRegex matches a parameter of an authentication challenge,
+// such as realm="https://auth.docker.io/token".
+var challengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// token requests an anonymous token for the Bearer challenge in a
+// WWW-Authenticate header, such as
+//
+//	Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/golang:pull"
+func (c *Client) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+	values := url.Values{}
+	var realm string
+	for _, m := range challengeParamRegex.FindAllStringSubmatch(params, -1) {
+		switch m[1] {
+		case "realm":
+			realm = m[2]
+		case "service", "scope":
+			values.Set(m[1], m[2])
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("registry authentication challenge %q has no realm", challenge)
+	}
+	u := realm
+	if len(values) > 0 {
+		u += "?" + values.Encode()
+	}
+	resp, err := c.do(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get registry token: %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("failed to get registry token: empty token")
+}
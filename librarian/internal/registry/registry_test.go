@@ -0,0 +1,159 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeRegistry serves the tags and manifests of one repository, requiring a
+// token from its /token endpoint like a public registry does.
+type fakeRegistry struct {
+	repo      string
+	pages     [][]string        // tags, one page per request
+	manifests map[string]string // manifest body by tag
+	noDigest  bool              // omit the Docker-Content-Digest header
+}
+
+func (f *fakeRegistry) start(t *testing.T) string {
+	t.Helper()
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("scope"), "repository:"+f.repo+":pull"; got != want {
+			http.Error(w, fmt.Sprintf("scope = %q, want %q", got, want), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"token": "secret"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="repository:%s:pull"`, srv.URL, f.repo))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v2/"+f.repo)
+		switch {
+		case path == "/tags/list":
+			page := 0
+			fmt.Sscan(r.URL.Query().Get("page"), &page)
+			if page+1 < len(f.pages) {
+				w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?page=%d>; rel="next"`, f.repo, page+1))
+			}
+			fmt.Fprintf(w, `{"name": %q, "tags": ["%s"]}`, f.repo, strings.Join(f.pages[page], `", "`))
+		case strings.HasPrefix(path, "/manifests/"):
+			manifest, ok := f.manifests[strings.TrimPrefix(path, "/manifests/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+				http.Error(w, "missing Accept header", http.StatusBadRequest)
+				return
+			}
+			if !f.noDigest {
+				w.Header().Set("Docker-Content-Digest", digestOf(manifest))
+			}
+			fmt.Fprint(w, manifest)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://") + "/" + f.repo
+}
+
+func digestOf(manifest string) string {
+	sum := sha256.Sum256([]byte(manifest))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestLatest(t *testing.T) {
+	for _, noDigest := range []bool{false, true} {
+		t.Run(fmt.Sprintf("noDigest=%v", noDigest), func(t *testing.T) {
+			f := &fakeRegistry{
+				repo: "images-prod/python-librarian-generator",
+				pages: [][]string{
+					{"latest", "v1.2.0", "v1.10.0-rc.1"},
+					{"v1.9.0", "sha256-abc.sig"},
+				},
+				manifests: map[string]string{
+					"v1.9.0": `{"schemaVersion": 2, "tag": "v1.9.0"}`,
+				},
+				noDigest: noDigest,
+			}
+			image := f.start(t)
+			tag, digest, err := (&Client{}).Latest(context.Background(), image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tag != "v1.9.0" {
+				t.Errorf("tag = %q, want %q", tag, "v1.9.0")
+			}
+			if want := digestOf(f.manifests["v1.9.0"]); digest != want {
+				t.Errorf("digest = %q, want %q", digest, want)
+			}
+		})
+	}
+}
+
+func TestDigestNotFound(t *testing.T) {
+	f := &fakeRegistry{repo: "gen", pages: [][]string{{"v1.0.0"}}}
+	image := f.start(t)
+	_, err := (&Client{}).Digest(context.Background(), image, "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Digest() = %v, want 404 error", err)
+	}
+}
+
+func TestLatestTag(t *testing.T) {
+	for _, test := range []struct {
+		tags []string
+		want string
+	}{
+		{[]string{"v1.2.0", "v1.10.0", "v1.9.3"}, "v1.10.0"},
+		{[]string{"1.2.0", "2.0.0-beta.1"}, "1.2.0"},
+		{[]string{"latest", "main"}, "latest"},
+		{[]string{"main"}, ""},
+	} {
+		if got := LatestTag(test.tags); got != test.want {
+			t.Errorf("LatestTag(%q) = %q, want %q", test.tags, got, test.want)
+		}
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	for _, test := range []struct {
+		image string
+		want  Reference
+	}{
+		{
+			image: "us-central1-docker.pkg.dev/cloud-sdk-librarian-prod/images-prod/python-librarian-generator",
+			want:  Reference{Host: "us-central1-docker.pkg.dev", Repository: "cloud-sdk-librarian-prod/images-prod/python-librarian-generator"},
+		},
+		{image: "localhost:5000/gen", want: Reference{Host: "localhost:5000", Repository: "gen"}},
+		{image: "golang", want: Reference{Host: "registry-1.docker.io", Repository: "library/golang"}},
+		{image: "user/gen", want: Reference{Host: "registry-1.docker.io", Repository: "user/gen"}},
+	} {
+		got, err := ParseReference(test.image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("ParseReference(%q) mismatch (-want +got):\n%s", test.image, diff)
+		}
+	}
+	for _, image := range []string{"", "gen:v1.0.0", "example.com/gen@sha256:abc"} {
+		if _, err := ParseReference(image); err == nil {
+			t.Errorf("ParseReference(%q) succeeded, want error", image)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/julieqiu/exp/librarian/internal/config"
@@ -41,17 +43,117 @@ func DetectPrerelease(cfg *config.Config) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if pattern := MatchBranch(cfg, branch); pattern != nil {
+		return pattern.Prerelease, nil
+	}
+	return "", nil
+}
 
-	// Match against patterns
-	for _, pattern := range cfg.Release.BranchPatterns {
+// MatchBranch returns the first configured branch pattern matching branch,
+// or nil if none matches.
+func MatchBranch(cfg *config.Config, branch string) *config.BranchPattern {
+	if cfg.Release == nil {
+		return nil
+	}
+	for i, pattern := range cfg.Release.BranchPatterns {
 		matched, err := filepath.Match(pattern.Pattern, branch)
 		if err != nil {
 			continue
 		}
 		if matched {
-			return pattern.Prerelease, nil
+			return &cfg.Release.BranchPatterns[i]
 		}
 	}
+	return nil
+}
 
-	return "", nil
+// IsMaintenanceBranch reports whether branch matches a branch pattern marked
+// as a maintenance branch.
+func IsMaintenanceBranch(cfg *config.Config, branch string) bool {
+	pattern := MatchBranch(cfg, branch)
+	return pattern != nil && pattern.Maintenance
+}
+
+// Series is the version series that a maintenance branch releases, such as
+// 1.x or 1.2.x.
+type Series struct {
+	Major int
+	Minor int // -1 if the series covers every minor version of Major
+}
+
+// seriesRegex matches the version series at the end of a maintenance branch
+// name, such as "v1.x" in "release/v1.x" or "1.2.x" in "1.2.x".
+var seriesRegex = regexp.MustCompile(`(?:^|[/_-])v?(0|[1-9]\d*)(?:\.(0|[1-9]\d*))?\.x$`)
+
+// ParseSeries returns the version series of a maintenance branch, taken from
+// the end of its name.
+//
+// Examples:
+//   - ParseSeries("release/v1.x") -> 1.x
+//   - ParseSeries("v2.3.x") -> 2.3.x
+func ParseSeries(branch string) (Series, error) {
+	m := seriesRegex.FindStringSubmatch(branch)
+	if m == nil {
+		return Series{}, fmt.Errorf("cannot determine the version series of branch %q; maintenance branch names must end with a series such as v1.x or v1.2.x", branch)
+	}
+	s := Series{Minor: -1}
+	s.Major, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		s.Minor, _ = strconv.Atoi(m[2])
+	}
+	return s, nil
+}
+
+// Contains reports whether v belongs to the series.
+func (s Series) Contains(v Version) bool {
+	return v.Major == s.Major && (s.Minor < 0 || v.Minor == s.Minor)
+}
+
+func (s Series) String() string {
+	if s.Minor < 0 {
+		return fmt.Sprintf("%d.x", s.Major)
+	}
+	return fmt.Sprintf("%d.%d.x", s.Major, s.Minor)
+}
+
+// BranchName returns the name of the maintenance branch for the major
+// version series of v: the first maintenance branch pattern, with its "*"
+// replaced by the major version. For example, the pattern "release/v*.x"
+// gives "release/v1.x" for v1.2.0.
+func BranchName(cfg *config.Config, v Version) (string, error) {
+	if cfg.Release != nil {
+		for _, pattern := range cfg.Release.BranchPatterns {
+			if !pattern.Maintenance {
+				continue
+			}
+			if strings.Count(pattern.Pattern, "*") != 1 {
+				return "", fmt.Errorf("cannot derive a branch name from pattern %q; pass the branch name with --name", pattern.Pattern)
+			}
+			name := strings.Replace(pattern.Pattern, "*", strconv.Itoa(v.Major), 1)
+			if _, err := ParseSeries(name); err != nil {
+				return "", fmt.Errorf("cannot derive a branch name from pattern %q; pass the branch name with --name", pattern.Pattern)
+			}
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no maintenance branch pattern is configured in release.branch_patterns")
+}
+
+// BranchExists reports whether the local branch exists.
+func BranchExists(branch string) (bool, error) {
+	cmd := exec.Command("git", "branch", "--list", branch)
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list branches: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(output)), "*")) == branch, nil
+}
+
+// CreateBranch creates the local branch at ref, without checking it out.
+func CreateBranch(branch, ref string) error {
+	cmd := exec.Command("git", "branch", branch, ref)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w\n%s", branch, err, output)
+	}
+	return nil
 }
@@ -0,0 +1,93 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/julieqiu/exp/librarian/internal/config"
+)
+
+func TestParseSeries(t *testing.T) {
+	for _, test := range []struct {
+		branch string
+		want   string
+	}{
+		{"release/v1.x", "1.x"},
+		{"release/1.x", "1.x"},
+		{"v2.3.x", "2.3.x"},
+		{"maint-v0.4.x", "0.4.x"},
+	} {
+		t.Run(test.branch, func(t *testing.T) {
+			got, err := ParseSeries(test.branch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != test.want {
+				t.Errorf("ParseSeries(%q) = %s, want %s", test.branch, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSeriesError(t *testing.T) {
+	for _, branch := range []string{"main", "release/v1", "release/v1.x.y", "release/foo1.x"} {
+		if _, err := ParseSeries(branch); err == nil {
+			t.Errorf("ParseSeries(%q) succeeded, want error", branch)
+		}
+	}
+}
+
+func TestSeriesContains(t *testing.T) {
+	major := Series{Major: 1, Minor: -1}
+	minor := Series{Major: 1, Minor: 2}
+	for _, test := range []struct {
+		version string
+		inMajor bool
+		inMinor bool
+	}{
+		{"v1.2.3", true, true},
+		{"v1.3.0", true, false},
+		{"v2.2.0", false, false},
+	} {
+		v, err := ParseVersion(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := major.Contains(v); got != test.inMajor {
+			t.Errorf("%s.Contains(%s) = %v, want %v", major, test.version, got, test.inMajor)
+		}
+		if got := minor.Contains(v); got != test.inMinor {
+			t.Errorf("%s.Contains(%s) = %v, want %v", minor, test.version, got, test.inMinor)
+		}
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	cfg := &config.Config{Release: &config.ReleaseConfig{
+		BranchPatterns: []config.BranchPattern{
+			{Pattern: "main"},
+			{Pattern: "release/v*.x", Maintenance: true},
+		},
+	}}
+	v, err := ParseVersion("v1.4.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := BranchName(cfg, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "release/v1.x"; got != want {
+		t.Errorf("BranchName() = %q, want %q", got, want)
+	}
+	if !IsMaintenanceBranch(cfg, got) {
+		t.Errorf("IsMaintenanceBranch(%q) = false, want true", got)
+	}
+	if IsMaintenanceBranch(cfg, "main") {
+		t.Error("IsMaintenanceBranch(\"main\") = true, want false")
+	}
+
+	cfg.Release.BranchPatterns[1].Pattern = "release/*"
+	if _, err := BranchName(cfg, v); err == nil {
+		t.Error("BranchName() succeeded for a pattern without a version series, want error")
+	}
+}
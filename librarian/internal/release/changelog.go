@@ -0,0 +1,158 @@
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const changelogFile = "CHANGES.md"
+
+// changelogSections lists the commit types included in release notes, in the
+// order they appear.
+var changelogSections = []struct {
+	Type    string
+	Section string
+}{
+	{Type: "feat", Section: "Features"},
+	{Type: "fix", Section: "Bug Fixes"},
+	{Type: "perf", Section: "Performance Improvements"},
+	{Type: "revert", Section: "Reverts"},
+	{Type: "docs", Section: "Documentation"},
+}
+
+// Notes returns markdown release notes for commits, grouped by commit type.
+// Breaking changes are listed first in their own section. Commits with types
+// not in changelogSections are omitted.
+func Notes(commits []*Commit) string {
+	var breaking []*Commit
+	byType := make(map[string][]*Commit)
+	for _, c := range commits {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+		byType[c.Type] = append(byType[c.Type], c)
+	}
+
+	var b strings.Builder
+	writeSection(&b, "⚠ BREAKING CHANGES", breaking)
+	for _, section := range changelogSections {
+		writeSection(&b, section.Section, byType[section.Type])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// DependencyNotes returns a markdown release notes section listing
+// dependency updates.
+func DependencyNotes(updates []string) string {
+	var b strings.Builder
+	b.WriteString("### Dependencies\n\n")
+	for _, u := range updates {
+		fmt.Fprintf(&b, "* %s\n", u)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// writeSection writes a markdown section listing commits, sorted and
+// deduplicated by subject. Empty sections are omitted.
+func writeSection(b *strings.Builder, title string, commits []*Commit) {
+	if len(commits) == 0 {
+		return
+	}
+	bySubject := make(map[string]*Commit)
+	for _, c := range commits {
+		if _, ok := bySubject[c.Subject]; !ok {
+			bySubject[c.Subject] = c
+		}
+	}
+	var subjects []string
+	for s := range bySubject {
+		subjects = append(subjects, s)
+	}
+	sort.Strings(subjects)
+
+	fmt.Fprintf(b, "### %s\n\n", title)
+	for _, s := range subjects {
+		c := bySubject[s]
+		if c.Scope != "" {
+			s = fmt.Sprintf("**%s:** %s", c.Scope, s)
+		}
+		if hash := shortHash(c.Hash); hash != "" {
+			fmt.Fprintf(b, "* %s (%s)\n", s, hash)
+		} else {
+			fmt.Fprintf(b, "* %s\n", s)
+		}
+	}
+	b.WriteString("\n")
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// changelogTitleRegex matches the "# Changes" title line of a changelog.
+var changelogTitleRegex = regexp.MustCompile(`(?m)^# Changes[ \t]*\r?$`)
+
+// changelogHeadingRegex matches the heading of any version entry.
+var changelogHeadingRegex = regexp.MustCompile(`(?m)^## `)
+
+// UpdateChangelog adds an entry for version with the given notes to the
+// CHANGES.md file in dir, creating the file if needed. New entries are
+// inserted after the "# Changes" title, above older entries. If the file
+// already has an entry for version, it is replaced.
+func UpdateChangelog(dir, version, notes string, t time.Time) error {
+	path := filepath.Join(dir, changelogFile)
+	oldContent, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read changelog: %w", err)
+	}
+
+	var entry bytes.Buffer
+	fmt.Fprintf(&entry, "## %s (%s)\n\n", version, t.Format("2006-01-02"))
+	if notes != "" {
+		entry.WriteString(notes)
+		entry.WriteString("\n\n")
+	}
+
+	var newContent []byte
+	versionRegex := regexp.MustCompile(`(?m)^## ` + regexp.QuoteMeta(version) + `(?:[ \t].*)?\r?$`)
+	if loc := versionRegex.FindIndex(oldContent); loc != nil {
+		// Replace the existing entry, up to the next entry or the end of
+		// the file.
+		end := len(oldContent)
+		if next := changelogHeadingRegex.FindIndex(oldContent[loc[1]:]); next != nil {
+			end = loc[1] + next[0]
+		}
+		newContent = append(newContent, oldContent[:loc[0]]...)
+		newContent = append(newContent, entry.Bytes()...)
+		newContent = append(newContent, oldContent[end:]...)
+	} else if loc := changelogTitleRegex.FindIndex(oldContent); loc != nil {
+		// Insert after the title and any blank lines that follow it.
+		insertionPoint := loc[1]
+		for insertionPoint < len(oldContent) && (oldContent[insertionPoint] == '\n' || oldContent[insertionPoint] == '\r') {
+			insertionPoint++
+		}
+		newContent = append(newContent, oldContent[:loc[1]]...)
+		newContent = append(newContent, "\n\n"...)
+		newContent = append(newContent, entry.Bytes()...)
+		newContent = append(newContent, oldContent[insertionPoint:]...)
+	} else {
+		newContent = append(newContent, "# Changes\n\n"...)
+		newContent = append(newContent, entry.Bytes()...)
+		newContent = append(newContent, oldContent...)
+	}
+	newContent = append(bytes.TrimRight(newContent, "\n"), '\n')
+
+	if err := os.WriteFile(path, newContent, 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
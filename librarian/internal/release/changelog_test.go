@@ -0,0 +1,94 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNotes(t *testing.T) {
+	commits := []*Commit{
+		ParseCommit("1111111111", "feat: add GetSecret API"),
+		ParseCommit("2222222222", "fix(secretmanager): correct retry settings"),
+		ParseCommit("3333333333", "feat!: remove deprecated field"),
+		ParseCommit("4444444444", "chore: update dependencies"),
+		ParseCommit("5555555555", "feat: add GetSecret API"),
+	}
+	want := `### ⚠ BREAKING CHANGES
+
+* remove deprecated field (3333333)
+
+### Features
+
+* add GetSecret API (1111111)
+* remove deprecated field (3333333)
+
+### Bug Fixes
+
+* **secretmanager:** correct retry settings (2222222)`
+	if diff := cmp.Diff(want, Notes(commits)); diff != "" {
+		t.Errorf("Notes() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdateChangelog(t *testing.T) {
+	date := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name    string
+		initial string
+		want    string
+	}{
+		{
+			name: "new file",
+			want: "# Changes\n\n## v1.3.0 (2025-10-01)\n\n### Features\n\n* new API\n",
+		},
+		{
+			name:    "existing entries",
+			initial: "# Changes\n\n## v1.2.0 (2025-09-01)\n\n* old\n",
+			want:    "# Changes\n\n## v1.3.0 (2025-10-01)\n\n### Features\n\n* new API\n\n## v1.2.0 (2025-09-01)\n\n* old\n",
+		},
+		{
+			name:    "replaces stale entry",
+			initial: "# Changes\n\n## v1.3.0 (2025-09-30)\n\n* stale\n\n## v1.2.0 (2025-09-01)\n\n* old\n",
+			want:    "# Changes\n\n## v1.3.0 (2025-10-01)\n\n### Features\n\n* new API\n\n## v1.2.0 (2025-09-01)\n\n* old\n",
+		},
+		{
+			name:    "replaces last entry",
+			initial: "# Changes\n\n## v1.3.0 (2025-09-30)\n\n* stale\n",
+			want:    "# Changes\n\n## v1.3.0 (2025-10-01)\n\n### Features\n\n* new API\n",
+		},
+		{
+			name:    "does not match other versions",
+			initial: "# Changes\n\n## v1.3.0-rc.1 (2025-09-30)\n\n* rc\n",
+			want:    "# Changes\n\n## v1.3.0 (2025-10-01)\n\n### Features\n\n* new API\n\n## v1.3.0-rc.1 (2025-09-30)\n\n* rc\n",
+		},
+		{
+			name:    "second-level heading is not the title",
+			initial: "Intro\n\n## Changes\n\n* notes\n",
+			want:    "# Changes\n\n## v1.3.0 (2025-10-01)\n\n### Features\n\n* new API\n\nIntro\n\n## Changes\n\n* notes\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, changelogFile)
+			if test.initial != "" {
+				if err := os.WriteFile(path, []byte(test.initial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := UpdateChangelog(dir, "v1.3.0", "### Features\n\n* new API", date); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
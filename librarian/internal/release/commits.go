@@ -0,0 +1,132 @@
+package release
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Bump is the kind of version increment implied by a set of changes.
+type Bump int
+
+const (
+	// BumpNone means there are no releasable changes.
+	BumpNone Bump = iota
+	// BumpPatch increments the patch version.
+	BumpPatch
+	// BumpMinor increments the minor version.
+	BumpMinor
+	// BumpMajor increments the major version.
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	default:
+		return "none"
+	}
+}
+
+// Commit is a git commit parsed as a conventional commit.
+type Commit struct {
+	Hash     string
+	Type     string // "feat", "fix", etc. Empty if the subject is not conventional.
+	Scope    string
+	Subject  string // Subject without the type and scope prefix.
+	Body     string
+	Breaking bool
+}
+
+var conventionalRegex = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// ParseCommit parses a commit message using the conventional commits format.
+// Messages that do not follow the format are returned with an empty Type.
+func ParseCommit(hash, message string) *Commit {
+	message = strings.TrimSpace(message)
+	subject, body, _ := strings.Cut(message, "\n")
+	c := &Commit{
+		Hash:    hash,
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.TrimSpace(body),
+	}
+	if m := conventionalRegex.FindStringSubmatch(c.Subject); m != nil {
+		c.Type = strings.ToLower(m[1])
+		c.Scope = m[2]
+		c.Breaking = m[3] == "!"
+		c.Subject = m[4]
+	}
+	for _, line := range strings.Split(c.Body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			c.Breaking = true
+		}
+	}
+	return c
+}
+
+// Bump returns the version increment implied by the commit.
+func (c *Commit) Bump() Bump {
+	switch {
+	case c.Breaking:
+		return BumpMajor
+	case c.Type == "feat":
+		return BumpMinor
+	case c.Type == "fix":
+		return BumpPatch
+	default:
+		return BumpNone
+	}
+}
+
+// DetermineBump returns the largest version increment implied by commits.
+func DetermineBump(commits []*Commit) Bump {
+	bump := BumpNone
+	for _, c := range commits {
+		if b := c.Bump(); b > bump {
+			bump = b
+		}
+	}
+	return bump
+}
+
+// commitSeparator separates commits in the output of git log.
+const commitSeparator = "\x1e"
+
+// CommitsSince returns the commits reachable from HEAD but not from tag that
+// touch files under dir, newest first. If tag is empty, all commits touching
+// dir are returned. Commits that only change .librarian.yaml files, such as
+// those recording a release, are not returned.
+func CommitsSince(tag, dir string) ([]*Commit, error) {
+	args := []string{"log", "--format=%H%x00%B" + commitSeparator}
+	if tag != "" {
+		args = append(args, tag+"..HEAD")
+	}
+	args = append(args, "--", dir, ":(exclude,glob)"+path.Join(filepath.ToSlash(dir), "**/.librarian.yaml"))
+	cmd := exec.Command("git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git log for %s: %w", dir, err)
+	}
+
+	var commits []*Commit
+	for _, entry := range strings.Split(string(output), commitSeparator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		hash, message, ok := strings.Cut(entry, "\x00")
+		if !ok {
+			return nil, fmt.Errorf("unexpected git log output: %q", entry)
+		}
+		commits = append(commits, ParseCommit(hash, message))
+	}
+	return commits, nil
+}
@@ -0,0 +1,72 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCommit(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		message string
+		want    *Commit
+	}{
+		{
+			name:    "feature",
+			message: "feat: add GetSecret API",
+			want:    &Commit{Hash: "abc", Type: "feat", Subject: "add GetSecret API"},
+		},
+		{
+			name:    "scoped fix",
+			message: "fix(secretmanager): correct retry settings\n\nDetails here.",
+			want:    &Commit{Hash: "abc", Type: "fix", Scope: "secretmanager", Subject: "correct retry settings", Body: "Details here."},
+		},
+		{
+			name:    "breaking marker",
+			message: "feat!: remove deprecated field",
+			want:    &Commit{Hash: "abc", Type: "feat", Subject: "remove deprecated field", Breaking: true},
+		},
+		{
+			name:    "breaking footer",
+			message: "fix: rename method\n\nBREAKING CHANGE: Get is now Fetch",
+			want:    &Commit{Hash: "abc", Type: "fix", Subject: "rename method", Body: "BREAKING CHANGE: Get is now Fetch", Breaking: true},
+		},
+		{
+			name:    "not conventional",
+			message: "Update README",
+			want:    &Commit{Hash: "abc", Subject: "Update README"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := ParseCommit("abc", test.message)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ParseCommit() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetermineBump(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		messages []string
+		want     Bump
+	}{
+		{"no commits", nil, BumpNone},
+		{"chores only", []string{"chore: update deps", "docs: fix typo"}, BumpNone},
+		{"fix", []string{"fix: a bug", "chore: cleanup"}, BumpPatch},
+		{"feature", []string{"fix: a bug", "feat: new API"}, BumpMinor},
+		{"breaking", []string{"feat: new API", "refactor!: drop v1"}, BumpMajor},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var commits []*Commit
+			for _, m := range test.messages {
+				commits = append(commits, ParseCommit("", m))
+			}
+			if got := DetermineBump(commits); got != test.want {
+				t.Errorf("DetermineBump() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
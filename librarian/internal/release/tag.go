@@ -0,0 +1,134 @@
+package release
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultTagFormat is used when the repository does not configure
+// release.tag_format.
+const DefaultTagFormat = "v{version}"
+
+// TagFields holds the values substituted into a tag format.
+type TagFields struct {
+	ID      string // {id}: the artifact ID, the last element of its directory
+	Name    string // {name}: the package name, defaulting to the artifact ID
+	Path    string // {path}: the artifact directory relative to the repository root
+	Version string // {version}: the version, without a leading "v"
+}
+
+var placeholderRegex = regexp.MustCompile(`\{[^{}]*\}`)
+
+// FormatTag expands the placeholders in format using fields.
+// Supported placeholders are {id}, {name}, {path}, and {version}.
+//
+// Examples, for an artifact at packages/secretmanager with version v1.2.0:
+//   - FormatTag("{name}-v{version}", ...) -> "secretmanager-v1.2.0"
+//   - FormatTag("{path}/v{version}", ...) -> "packages/secretmanager/v1.2.0"
+func FormatTag(format string, fields TagFields) (string, error) {
+	if format == "" {
+		format = DefaultTagFormat
+	}
+	if !strings.Contains(format, "{version}") {
+		return "", fmt.Errorf("tag format %q must contain {version}", format)
+	}
+
+	values := map[string]string{
+		"{id}":      fields.ID,
+		"{name}":    fields.Name,
+		"{path}":    strings.Trim(fields.Path, "/"),
+		"{version}": strings.TrimPrefix(fields.Version, "v"),
+	}
+	if values["{name}"] == "" {
+		values["{name}"] = fields.ID
+	}
+
+	var errs []string
+	tag := placeholderRegex.ReplaceAllStringFunc(format, func(p string) string {
+		v, ok := values[p]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown placeholder %s", p))
+			return p
+		}
+		if v == "" || v == "." {
+			errs = append(errs, fmt.Sprintf("no value for placeholder %s", p))
+		}
+		return v
+	})
+	if len(errs) > 0 {
+		return "", fmt.Errorf("invalid tag format %q: %s", format, strings.Join(errs, ", "))
+	}
+	if strings.ContainsAny(tag, " \t\n~^:?*[\\") {
+		return "", fmt.Errorf("tag format %q produced invalid tag %q", format, tag)
+	}
+	return tag, nil
+}
+
+// SharedTags returns the tags in tags that belong to more than one
+// artifact, each with the sorted paths of those artifacts. tags maps each
+// artifact path to its tag.
+func SharedTags(tags map[string]string) map[string][]string {
+	byTag := make(map[string][]string)
+	for path, tag := range tags {
+		byTag[tag] = append(byTag[tag], path)
+	}
+	shared := make(map[string][]string)
+	for tag, paths := range byTag {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			shared[tag] = paths
+		}
+	}
+	return shared
+}
+
+// TagExists reports whether the git tag exists in the local repository.
+func TagExists(tag string) (bool, error) {
+	cmd := exec.Command("git", "tag", "--list", tag)
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == tag, nil
+}
+
+// TagVersions returns the versions of the git tags in the local repository
+// that match format with fields, in no particular order. fields.Version is
+// ignored. Versions are returned as they appear in the tags, without a
+// leading "v" added.
+func TagVersions(format string, fields TagFields) ([]string, error) {
+	re, err := tagRegex(format, fields)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "tag", "--list")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	var versions []string
+	for _, tag := range strings.Fields(string(output)) {
+		if m := re.FindStringSubmatch(tag); m != nil {
+			versions = append(versions, m[1])
+		}
+	}
+	return versions, nil
+}
+
+// tagRegex returns a regular expression matching the tags formatted by
+// FormatTag with fields and any version, capturing the version.
+func tagRegex(format string, fields TagFields) (*regexp.Regexp, error) {
+	// Format with a placeholder version that is quoted along with the rest
+	// of the tag, then replace it with a capturing group.
+	const marker = "0.0.0-version"
+	fields.Version = marker
+	tag, err := FormatTag(format, fields)
+	if err != nil {
+		return nil, err
+	}
+	p := strings.Replace(regexp.QuoteMeta(tag), regexp.QuoteMeta(marker), `([^/]+)`, 1)
+	return regexp.MustCompile("^" + p + "$"), nil
+}
@@ -0,0 +1,117 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormatTag(t *testing.T) {
+	fields := TagFields{
+		ID:      "secretmanager",
+		Name:    "google-cloud-secret-manager",
+		Path:    "packages/secretmanager",
+		Version: "v1.2.0",
+	}
+	for _, test := range []struct {
+		format string
+		want   string
+	}{
+		{"", "v1.2.0"},
+		{"{name}-v{version}", "google-cloud-secret-manager-v1.2.0"},
+		{"{id}/v{version}", "secretmanager/v1.2.0"},
+		{"{path}/v{version}", "packages/secretmanager/v1.2.0"},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			got, err := FormatTag(test.format, fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("FormatTag(%q) = %q, want %q", test.format, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatTagDefaultName(t *testing.T) {
+	got, err := FormatTag("{name}-v{version}", TagFields{ID: "secretmanager", Version: "1.2.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "secretmanager-v1.2.0"; got != want {
+		t.Errorf("FormatTag() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatTagPEP440(t *testing.T) {
+	got, err := FormatTag("{name}-v{version}", TagFields{ID: "secretmanager", Name: "google-cloud-secret-manager", Version: "1.3.0rc1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "google-cloud-secret-manager-v1.3.0rc1"; got != want {
+		t.Errorf("FormatTag() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatTagError(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		format string
+		fields TagFields
+	}{
+		{"unknown placeholder", "{package}-v{version}", TagFields{ID: "a", Version: "1.0.0"}},
+		{"missing version", "{id}", TagFields{ID: "a", Version: "1.0.0"}},
+		{"root path", "{path}/v{version}", TagFields{ID: "a", Path: ".", Version: "1.0.0"}},
+		{"invalid character", "{id} v{version}", TagFields{ID: "a", Version: "1.0.0"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := FormatTag(test.format, test.fields); err == nil {
+				t.Errorf("FormatTag(%q) succeeded, want error", test.format)
+			}
+		})
+	}
+}
+
+func TestTagRegex(t *testing.T) {
+	fields := TagFields{ID: "secretmanager", Name: "google-cloud-secret-manager", Path: "packages/secretmanager"}
+	for _, test := range []struct {
+		format string
+		tag    string
+		want   string // empty if the tag does not match
+	}{
+		{"", "v1.2.0", "1.2.0"},
+		{"{name}-v{version}", "google-cloud-secret-manager-v1.3.0rc1", "1.3.0rc1"},
+		{"{name}-v{version}", "google-cloud-storage-v1.3.0", ""},
+		{"{path}/v{version}", "packages/secretmanager/v0.4.1", "0.4.1"},
+		{"{path}/v{version}", "packages/secretmanager/apiv1/v0.4.1", ""},
+	} {
+		t.Run(test.tag, func(t *testing.T) {
+			re, err := tagRegex(test.format, fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if m := re.FindStringSubmatch(test.tag); m != nil {
+				got = m[1]
+			}
+			if got != test.want {
+				t.Errorf("tagRegex(%q) matched %q in %q, want %q", test.format, got, test.tag, test.want)
+			}
+		})
+	}
+}
+
+func TestSharedTags(t *testing.T) {
+	got := SharedTags(map[string]string{
+		"packages/a/storage": "storage-v0.0.0",
+		"packages/b/storage": "storage-v0.0.0",
+		"packages/pubsub":    "pubsub-v0.0.0",
+	})
+	want := map[string][]string{
+		"storage-v0.0.0": {"packages/a/storage", "packages/b/storage"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SharedTags() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -7,67 +7,490 @@ import (
 	"strings"
 )
 
-var versionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([a-z]+)\.(\d+))?$`)
+// Scheme is a versioning scheme.
+type Scheme int
 
-// IncrementVersion increments a version string with optional prerelease suffix.
+const (
+	// SemVer is Semantic Versioning 2.0.0 (https://semver.org), used by
+	// every language except Python.
+	SemVer Scheme = iota
+
+	// PEP440 is the Python version scheme
+	// (https://peps.python.org/pep-0440/).
+	PEP440
+)
+
+func (s Scheme) String() string {
+	switch s {
+	case SemVer:
+		return "semver"
+	case PEP440:
+		return "pep440"
+	}
+	return fmt.Sprintf("Scheme(%d)", int(s))
+}
+
+// NoVersion is the version recorded for an artifact that has never been
+// released.
+const NoVersion = "null"
+
+// Released reports whether version names a release, as opposed to being
+// empty or NoVersion.
+func Released(version string) bool {
+	return version != "" && version != NoVersion
+}
+
+// Version is a parsed release version.
+//
+// The same type represents SemVer and PEP 440 versions. PEP 440 prerelease
+// segments are stored in SemVer form: "1.2.0rc1" has Prerelease "rc.1".
+// PEP 440 post- and development releases have no SemVer equivalent and are
+// only kept for PEP 440 versions.
+type Version struct {
+	Scheme Scheme
+
+	// Prefix is "v" if the version was written with a leading "v". It is
+	// only kept for SemVer versions; PEP 440 versions are normalized
+	// without it.
+	Prefix string
+
+	Major, Minor, Patch int
+
+	// Prerelease holds the dot-separated prerelease identifiers, such as
+	// "rc.1" or "beta.1.rc".
+	Prerelease string
+
+	// Build holds the SemVer build metadata or PEP 440 local version label.
+	// It is ignored when comparing versions.
+	Build string
+
+	// post and dev are the PEP 440 post- and development-release numbers,
+	// or nil if absent.
+	post, dev *int
+}
+
+var (
+	semverRegex = regexp.MustCompile(`^(v?)(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+	// pep440Regex is the regular expression from PEP 440, Appendix B,
+	// without epochs and limited to two or three release components.
+	pep440Regex = regexp.MustCompile(`^(?i)v?(\d+)\.(\d+)(?:\.(\d+))?` +
+		`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d+)?)?` +
+		`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d+)?)?` +
+		`(?:[-_.]?(dev)[-_.]?(\d+)?)?` +
+		`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+	// pep440PreLabels maps PEP 440 prerelease spellings, and the prerelease
+	// names used in branch patterns, to normalized PEP 440 labels.
+	pep440PreLabels = map[string]string{
+		"a":       "a",
+		"alpha":   "a",
+		"b":       "b",
+		"beta":    "b",
+		"c":       "rc",
+		"rc":      "rc",
+		"pre":     "rc",
+		"preview": "rc",
+	}
+)
+
+// ParseVersion parses a SemVer version, falling back to PEP 440 for versions
+// that are not valid SemVer. A leading "v" is accepted.
+func ParseVersion(s string) (Version, error) {
+	if v, err := ParseSemVer(s); err == nil {
+		return v, nil
+	}
+	if v, err := ParsePEP440(s); err == nil {
+		return v, nil
+	}
+	return Version{}, fmt.Errorf("invalid version: %q", s)
+}
+
+// ParseVersionScheme parses s using scheme.
+func ParseVersionScheme(s string, scheme Scheme) (Version, error) {
+	if scheme == PEP440 {
+		return ParsePEP440(s)
+	}
+	return ParseSemVer(s)
+}
+
+// ParseSemVer parses a SemVer 2.0.0 version with an optional leading "v".
+func ParseSemVer(s string) (Version, error) {
+	m := semverRegex.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid semantic version: %q", s)
+	}
+	v := Version{
+		Scheme:     SemVer,
+		Prefix:     m[1],
+		Prerelease: m[5],
+		Build:      m[6],
+	}
+	var err error
+	if v.Major, err = strconv.Atoi(m[2]); err != nil {
+		return Version{}, fmt.Errorf("invalid semantic version: %q: %w", s, err)
+	}
+	if v.Minor, err = strconv.Atoi(m[3]); err != nil {
+		return Version{}, fmt.Errorf("invalid semantic version: %q: %w", s, err)
+	}
+	if v.Patch, err = strconv.Atoi(m[4]); err != nil {
+		return Version{}, fmt.Errorf("invalid semantic version: %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// ParsePEP440 parses a PEP 440 version. The version is normalized: for
+// example, "v1.2-beta.2" is parsed as 1.2.0b2. Epochs and release segments
+// with more than three components are not supported.
+func ParsePEP440(s string) (Version, error) {
+	m := pep440Regex.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid PEP 440 version: %q", s)
+	}
+	var nums [10]int
+	for _, i := range []int{1, 2, 3, 5, 6, 8, 10} {
+		if m[i] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i])
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid PEP 440 version: %q: %w", s, err)
+		}
+		nums[i-1] = n
+	}
+
+	v := Version{
+		Scheme: PEP440,
+		Major:  nums[0],
+		Minor:  nums[1],
+		Patch:  nums[2],
+		Build:  strings.ToLower(m[11]),
+	}
+	if m[4] != "" {
+		v.Prerelease = fmt.Sprintf("%s.%d", pep440PreLabels[strings.ToLower(m[4])], nums[4])
+	}
+	switch {
+	case m[6] != "":
+		v.post = &nums[5]
+	case m[7] != "":
+		v.post = &nums[7]
+	}
+	if m[9] != "" {
+		v.dev = &nums[9]
+	}
+	return v, nil
+}
+
+// String returns the version in the canonical form of its scheme.
+func (v Version) String() string {
+	if v.Scheme == PEP440 {
+		return v.pep440String()
+	}
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+func (v Version) pep440String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		label, n, _ := strings.Cut(v.Prerelease, ".")
+		if l, ok := pep440PreLabels[label]; ok {
+			label = l
+		}
+		b.WriteString(label + n)
+	}
+	if v.post != nil {
+		fmt.Fprintf(&b, ".post%d", *v.post)
+	}
+	if v.dev != nil {
+		fmt.Fprintf(&b, ".dev%d", *v.dev)
+	}
+	if v.Build != "" {
+		b.WriteString("+" + v.Build)
+	}
+	return b.String()
+}
+
+// IsPrerelease reports whether v is a prerelease. PEP 440 development
+// releases are prereleases.
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease != "" || v.dev != nil
+}
+
+// Stable returns the final release that v is a prerelease of. Build metadata
+// and PEP 440 post- and development-release numbers are removed.
+//
 // Examples:
-//   - IncrementVersion("v1.0.0", "") -> "v1.1.0"
-//   - IncrementVersion("v1.0.0", "rc") -> "v1.1.0-rc.1"
-//   - IncrementVersion("v1.0.0-rc.1", "rc") -> "v1.0.0-rc.2"
-func IncrementVersion(current, prerelease string) (string, error) {
-	if current == "" || current == "null" {
-		if prerelease != "" {
-			return "v0.1.0-" + prerelease + ".1", nil
+//   - v1.2.0-rc.1 -> v1.2.0
+//   - 1.2.0b2.dev0 -> 1.2.0
+func (v Version) Stable() Version {
+	return Version{
+		Scheme: v.Scheme,
+		Prefix: v.Prefix,
+		Major:  v.Major,
+		Minor:  v.Minor,
+		Patch:  v.Patch,
+	}
+}
+
+// Compare returns -1, 0, or +1 depending on whether v has lower, equal, or
+// higher precedence than w, using the precedence rules of v's scheme. Build
+// metadata and local version labels are ignored.
+func (v Version) Compare(w Version) int {
+	if c := compareInts(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+	if v.Scheme == PEP440 {
+		return comparePEP440Suffix(v, w)
+	}
+	return comparePrerelease(v.Prerelease, w.Prerelease)
+}
+
+// comparePrerelease compares SemVer prerelease identifiers. A version without
+// a prerelease has higher precedence than one with a prerelease.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
 		}
-		return "v0.1.0", nil
 	}
+	return compareInts(len(as), len(bs))
+}
 
-	matches := versionRegex.FindStringSubmatch(current)
-	if matches == nil {
-		return "", fmt.Errorf("invalid version format: %s", current)
+// compareIdentifier compares SemVer prerelease identifiers. Numeric
+// identifiers are compared numerically and have lower precedence than
+// alphanumeric identifiers, which are compared in ASCII order.
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
 	}
+	return strings.Compare(a, b)
+}
 
-	major, _ := strconv.Atoi(matches[1])
-	minor, _ := strconv.Atoi(matches[2])
-	patch, _ := strconv.Atoi(matches[3])
-	currentPre := matches[4]
-	currentPreNum := 0
-	if matches[5] != "" {
-		currentPreNum, _ = strconv.Atoi(matches[5])
+// comparePEP440Suffix compares the prerelease, post-release, and development
+// release segments of PEP 440 versions with equal release segments.
+func comparePEP440Suffix(v, w Version) int {
+	if c := comparePEP440Pre(v, w); c != 0 {
+		return c
 	}
+	// A post-release sorts after the same version without one.
+	if c := compareOptional(v.post, w.post, -1); c != 0 {
+		return c
+	}
+	// A development release sorts before the same version without one.
+	return compareOptional(v.dev, w.dev, 1)
+}
 
-	// If adding/keeping prerelease
+// comparePEP440Pre compares PEP 440 prerelease segments. A development
+// release of a final release ("1.0.dev0") sorts before all prereleases of it.
+func comparePEP440Pre(v, w Version) int {
+	rank := func(v Version) int {
+		switch {
+		case v.Prerelease != "":
+			return 0
+		case v.post == nil && v.dev != nil:
+			return -1
+		}
+		return 1
+	}
+	if c := compareInts(rank(v), rank(w)); c != 0 || v.Prerelease == "" {
+		return c
+	}
+	// Normalized labels "a" < "b" < "rc" sort in ASCII order.
+	return comparePrerelease(v.Prerelease, w.Prerelease)
+}
+
+// compareOptional compares optional numbers. A missing number compares as
+// lower than any number if missing is -1, and higher if missing is 1.
+func compareOptional(a, b *int, missing int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return missing
+	case b == nil:
+		return -missing
+	}
+	return compareInts(*a, *b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Next returns the version following v for a change of size bump, with an
+// optional prerelease name such as "rc" or "alpha".
+//
+// Versions before 1.0.0 follow pre-1.0 semantics: a breaking change (BumpMajor)
+// increments the minor version instead of the major version.
+//
+// If v is a prerelease with the same prerelease name, the prerelease number
+// is incremented instead. If v is a prerelease with an earlier name, such as
+// alpha before beta, the name is switched on the same version. If v is a
+// prerelease and prerelease is empty, the final release of v is returned.
+//
+// Examples:
+//   - v1.0.0.Next(BumpMinor, "") -> v1.1.0
+//   - v0.3.1.Next(BumpMajor, "") -> v0.4.0
+//   - v1.0.0.Next(BumpPatch, "rc") -> v1.0.1-rc.1
+//   - v1.0.0-rc.1.Next(BumpMinor, "rc") -> v1.0.0-rc.2
+//   - v1.0.0-alpha.2.Next(BumpMinor, "beta") -> v1.0.0-beta.1
+//   - 1.0.0rc1.Next(BumpMinor, "rc") -> 1.0.0rc2 (PEP 440)
+func (v Version) Next(bump Bump, prerelease string) (Version, error) {
+	if v.Scheme == PEP440 && prerelease != "" {
+		label, ok := pep440PreLabels[prerelease]
+		if !ok {
+			return Version{}, fmt.Errorf("prerelease %q is not supported by PEP 440 (use alpha, beta, or rc)", prerelease)
+		}
+		prerelease = label
+	}
+
+	// Continuing the same prerelease series
 	if prerelease != "" {
-		if currentPre == prerelease {
-			// Increment prerelease number
-			return fmt.Sprintf("v%d.%d.%d-%s.%d", major, minor, patch, prerelease, currentPreNum+1), nil
+		if n, ok := prereleaseNumber(v.Prerelease, prerelease); ok {
+			next := v.Stable()
+			next.Prerelease = fmt.Sprintf("%s.%d", prerelease, n+1)
+			return next, nil
+		}
+		// Moving to a later prerelease name of the same version
+		if v.IsPrerelease() {
+			next := v.Stable()
+			next.Prerelease = prerelease + ".1"
+			if next.Compare(v) > 0 {
+				return next, nil
+			}
 		}
-		// New prerelease type, increment minor
-		return fmt.Sprintf("v%d.%d.%d-%s.1", major, minor+1, 0, prerelease), nil
 	}
 
 	// Removing prerelease (promoting to stable)
-	if currentPre != "" {
-		// Just remove the prerelease suffix
-		return fmt.Sprintf("v%d.%d.%d", major, minor, patch), nil
+	if prerelease == "" && v.IsPrerelease() {
+		return v.Stable(), nil
+	}
+
+	next := v.Stable()
+	if next.Major == 0 && bump == BumpMajor {
+		bump = BumpMinor
+	}
+	switch bump {
+	case BumpMajor:
+		next.Major, next.Minor, next.Patch = next.Major+1, 0, 0
+	case BumpMinor:
+		next.Minor, next.Patch = next.Minor+1, 0
+	case BumpPatch:
+		next.Patch++
+	default:
+		return Version{}, fmt.Errorf("no changes to release from %s", v)
+	}
+	if prerelease != "" {
+		next.Prerelease = prerelease + ".1"
 	}
+	return next, nil
+}
 
-	// Regular increment (minor version)
-	return fmt.Sprintf("v%d.%d.%d", major, minor+1, 0), nil
+// prereleaseNumber returns N if pre is of the form "<name>.N".
+func prereleaseNumber(pre, name string) (int, bool) {
+	rest, ok := strings.CutPrefix(pre, name+".")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
 }
 
-// RemovePrerelease removes the prerelease suffix from a version.
+// FirstVersion returns the first version of an artifact that has never been
+// released: 0.1.0, with prerelease number 1 if prerelease is not empty.
+// SemVer versions are written with a leading "v".
+func FirstVersion(scheme Scheme, prerelease string) (Version, error) {
+	v := Version{Scheme: scheme, Minor: 1}
+	if scheme == SemVer {
+		v.Prefix = "v"
+	}
+	if prerelease == "" {
+		return v, nil
+	}
+	if scheme == PEP440 {
+		label, ok := pep440PreLabels[prerelease]
+		if !ok {
+			return Version{}, fmt.Errorf("prerelease %q is not supported by PEP 440 (use alpha, beta, or rc)", prerelease)
+		}
+		prerelease = label
+	}
+	v.Prerelease = prerelease + ".1"
+	return v, nil
+}
+
+// IncrementVersion returns the version following current for a change of
+// size bump, as described by Version.Next. If current is not Released, it
+// returns FirstVersion.
+//
 // Examples:
-//   - RemovePrerelease("v1.0.0-rc.1") -> "v1.0.0"
-//   - RemovePrerelease("v1.0.0") -> "v1.0.0"
-func RemovePrerelease(version string) string {
-	if idx := strings.Index(version, "-"); idx != -1 {
-		return version[:idx]
+//   - IncrementVersion("v1.0.0", SemVer, "", BumpMinor) -> "v1.1.0"
+//   - IncrementVersion("v1.0.0", SemVer, "", BumpMajor) -> "v2.0.0"
+//   - IncrementVersion("v0.3.1", SemVer, "", BumpMajor) -> "v0.4.0"
+//   - IncrementVersion("v1.0.0", SemVer, "rc", BumpPatch) -> "v1.0.1-rc.1"
+//   - IncrementVersion("v1.0.0-rc.1", SemVer, "rc", BumpMinor) -> "v1.0.0-rc.2"
+//   - IncrementVersion("1.0.0", PEP440, "beta", BumpMinor) -> "1.1.0b1"
+func IncrementVersion(current string, scheme Scheme, prerelease string, bump Bump) (string, error) {
+	if !Released(current) {
+		v, err := FirstVersion(scheme, prerelease)
+		if err != nil {
+			return "", err
+		}
+		return v.String(), nil
+	}
+	v, err := ParseVersionScheme(current, scheme)
+	if err != nil {
+		return "", err
+	}
+	next, err := v.Next(bump, prerelease)
+	if err != nil {
+		return "", err
 	}
-	return version
+	return next.String(), nil
 }
 
-// HasPrerelease returns true if the version has a prerelease suffix.
+// HasPrerelease reports whether version is a valid prerelease version.
 func HasPrerelease(version string) bool {
-	return strings.Contains(version, "-")
+	v, err := ParseVersion(version)
+	return err == nil && v.IsPrerelease()
 }
@@ -0,0 +1,235 @@
+package release
+
+import (
+	"testing"
+)
+
+func TestIncrementVersion(t *testing.T) {
+	for _, test := range []struct {
+		current    string
+		scheme     Scheme
+		prerelease string
+		bump       Bump
+		want       string
+	}{
+		{"null", SemVer, "", BumpNone, "v0.1.0"},
+		{"", SemVer, "rc", BumpMinor, "v0.1.0-rc.1"},
+		{"v1.2.3", SemVer, "", BumpPatch, "v1.2.4"},
+		{"v1.2.3", SemVer, "", BumpMinor, "v1.3.0"},
+		{"v1.2.3", SemVer, "", BumpMajor, "v2.0.0"},
+		{"v0.3.1", SemVer, "", BumpMajor, "v0.4.0"},
+		{"v0.3.1", SemVer, "", BumpMinor, "v0.4.0"},
+		{"v1.2.3", SemVer, "rc", BumpPatch, "v1.2.4-rc.1"},
+		{"v1.3.0-rc.1", SemVer, "rc", BumpMinor, "v1.3.0-rc.2"},
+		{"v1.3.0-rc.1", SemVer, "alpha", BumpMinor, "v1.4.0-alpha.1"},
+		{"v1.3.0-alpha.2", SemVer, "beta", BumpMinor, "v1.3.0-beta.1"},
+		{"v1.3.0-beta.3", SemVer, "rc", BumpMajor, "v1.3.0-rc.1"},
+		{"v1.3.0-rc.2", SemVer, "", BumpPatch, "v1.3.0"},
+		{"1.2.3", SemVer, "", BumpPatch, "1.2.4"},
+		{"v1.2.3+build.5", SemVer, "", BumpPatch, "v1.2.4"},
+		{"v1.3.0-beta.1.rc", SemVer, "beta", BumpPatch, "v1.3.1-beta.1"},
+		{"null", PEP440, "", BumpNone, "0.1.0"},
+		{"", PEP440, "beta", BumpMinor, "0.1.0b1"},
+		{"1.2.3", PEP440, "", BumpMinor, "1.3.0"},
+		{"v1.2.3", PEP440, "", BumpPatch, "1.2.4"},
+		{"1.2.3", PEP440, "rc", BumpPatch, "1.2.4rc1"},
+		{"1.3.0rc1", PEP440, "rc", BumpMinor, "1.3.0rc2"},
+		{"1.3.0b2", PEP440, "beta", BumpMinor, "1.3.0b3"},
+		{"1.3.0a2", PEP440, "beta", BumpMinor, "1.3.0b1"},
+		{"1.3.0rc2", PEP440, "", BumpPatch, "1.3.0"},
+		{"1.3.0.post1", PEP440, "", BumpPatch, "1.3.1"},
+		{"1.3.0.dev0", PEP440, "", BumpPatch, "1.3.0"},
+	} {
+		t.Run(test.current+"_"+test.scheme.String()+"_"+test.prerelease+"_"+test.bump.String(), func(t *testing.T) {
+			got, err := IncrementVersion(test.current, test.scheme, test.prerelease, test.bump)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("IncrementVersion(%q, %v, %q, %v) = %q, want %q", test.current, test.scheme, test.prerelease, test.bump, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIncrementVersionError(t *testing.T) {
+	for _, test := range []struct {
+		current    string
+		scheme     Scheme
+		prerelease string
+		bump       Bump
+	}{
+		{"v1.2.3", SemVer, "", BumpNone},
+		{"v1.2", SemVer, "", BumpPatch},
+		{"1.2.3rc1", SemVer, "", BumpPatch},
+		{"1.2.3", PEP440, "preview-2", BumpPatch},
+		{"1!1.2.3", PEP440, "", BumpPatch},
+	} {
+		if got, err := IncrementVersion(test.current, test.scheme, test.prerelease, test.bump); err == nil {
+			t.Errorf("IncrementVersion(%q, %v, %q, %v) = %q, want error", test.current, test.scheme, test.prerelease, test.bump, got)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for _, test := range []struct {
+		in         string
+		want       string
+		scheme     Scheme
+		prerelease bool
+	}{
+		{"v1.2.3", "v1.2.3", SemVer, false},
+		{"1.2.3", "1.2.3", SemVer, false},
+		{"v1.2.3-rc.1", "v1.2.3-rc.1", SemVer, true},
+		{"v1.2.3-beta.1.rc", "v1.2.3-beta.1.rc", SemVer, true},
+		{"v1.2.3-rc.1+build.20240101", "v1.2.3-rc.1+build.20240101", SemVer, true},
+		{"1.2.3+sha.abc", "1.2.3+sha.abc", SemVer, false},
+		{"1.2.3rc1", "1.2.3rc1", PEP440, true},
+		{"1.2.3-alpha.2", "1.2.3-alpha.2", SemVer, true},
+		{"1.2.3alpha2", "1.2.3a2", PEP440, true},
+		{"1.2b", "1.2.0b0", PEP440, true},
+		{"1.2.3.post2", "1.2.3.post2", PEP440, false},
+		{"1.2.3-1", "1.2.3-1", SemVer, true},
+		{"1.2.3.dev0", "1.2.3.dev0", PEP440, true},
+		{"1.2.3rc1.post1.dev2+ubuntu.1", "1.2.3rc1.post1.dev2+ubuntu.1", PEP440, true},
+		{"V1.0", "1.0.0", PEP440, false},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			v, err := ParseVersion(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.String(); got != test.want {
+				t.Errorf("ParseVersion(%q).String() = %q, want %q", test.in, got, test.want)
+			}
+			if v.Scheme != test.scheme {
+				t.Errorf("ParseVersion(%q).Scheme = %v, want %v", test.in, v.Scheme, test.scheme)
+			}
+			if got := v.IsPrerelease(); got != test.prerelease {
+				t.Errorf("ParseVersion(%q).IsPrerelease() = %t, want %t", test.in, got, test.prerelease)
+			}
+		})
+	}
+}
+
+func TestParseVersionError(t *testing.T) {
+	for _, in := range []string{"", "null", "v1", "v1.2.3-", "v1.2.3+", "v1.2.3-rc.1.", "1.2.3.4.5", "1!2.0.0", "latest"} {
+		if v, err := ParseVersion(in); err == nil {
+			t.Errorf("ParseVersion(%q) = %v, want error", in, v)
+		}
+	}
+}
+
+func TestParsePEP440(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"1.2.3", "1.2.3"},
+		{"v1.2.3", "1.2.3"},
+		{"1.2.3-rc.1", "1.2.3rc1"},
+		{"1.2.3-beta.2", "1.2.3b2"},
+		{"1.2.3c1", "1.2.3rc1"},
+		{"1.2.3-1", "1.2.3.post1"},
+		{"1.2.3.rev4", "1.2.3.post4"},
+		{"1.2.3-dev", "1.2.3.dev0"},
+		{"1.2.3+Local.7", "1.2.3+local.7"},
+	} {
+		v, err := ParsePEP440(test.in)
+		if err != nil {
+			t.Fatalf("ParsePEP440(%q): %v", test.in, err)
+		}
+		if got := v.String(); got != test.want {
+			t.Errorf("ParsePEP440(%q).String() = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Each list is in increasing order of precedence.
+	for _, test := range []struct {
+		scheme   Scheme
+		versions []string
+	}{
+		{
+			// Example from the SemVer 2.0.0 specification.
+			SemVer,
+			[]string{
+				"1.0.0-alpha",
+				"1.0.0-alpha.1",
+				"1.0.0-alpha.beta",
+				"1.0.0-beta",
+				"1.0.0-beta.2",
+				"1.0.0-beta.11",
+				"1.0.0-rc.1",
+				"1.0.0",
+				"v1.0.1",
+				"1.1.0",
+				"v2.0.0",
+			},
+		},
+		{
+			// Example from PEP 440, without epochs.
+			PEP440,
+			[]string{
+				"1.0.dev456",
+				"1.0a1",
+				"1.0a2.dev456",
+				"1.0a12.dev456",
+				"1.0a12",
+				"1.0b1.dev456",
+				"1.0b2",
+				"1.0b2.post345.dev456",
+				"1.0b2.post345",
+				"1.0rc1.dev456",
+				"1.0rc1",
+				"1.0",
+				"1.0.post456.dev34",
+				"1.0.post456",
+				"1.1.dev1",
+			},
+		},
+	} {
+		t.Run(test.scheme.String(), func(t *testing.T) {
+			for i, a := range test.versions {
+				for j, b := range test.versions {
+					v, err := ParseVersionScheme(a, test.scheme)
+					if err != nil {
+						t.Fatal(err)
+					}
+					w, err := ParseVersionScheme(b, test.scheme)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got, want := v.Compare(w), compareInts(i, j); got != want {
+						t.Errorf("Compare(%q, %q) = %d, want %d", a, b, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompareIgnoresBuild(t *testing.T) {
+	v, _ := ParseSemVer("v1.0.0+a")
+	w, _ := ParseSemVer("v1.0.0+b")
+	if got := v.Compare(w); got != 0 {
+		t.Errorf("Compare(%v, %v) = %d, want 0", v, w, got)
+	}
+}
+
+func TestHasPrerelease(t *testing.T) {
+	for _, test := range []struct {
+		version string
+		want    bool
+	}{
+		{"v1.0.0", false},
+		{"v1.0.0-rc.1", true},
+		{"1.0.0rc1", true},
+		{"1.0.0.post1", false},
+		{"null", false},
+	} {
+		if got := HasPrerelease(test.version); got != test.want {
+			t.Errorf("HasPrerelease(%q) = %t, want %t", test.version, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package source downloads source repository tarballs, such as googleapis,
+// and keeps them in a shared on-disk cache.
+//
+// Tarballs are cached by repository and commit and verified against a
+// SHA-256 checksum. Once a tarball has been downloaded, it is extracted into
+// the cache and later lookups for the same commit need no network access.
+// Only commit SHAs are cached, since the contents of a branch or tag can
+// change.
+package source
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CacheEnv is the environment variable that overrides the cache directory.
+const CacheEnv = "LIBRARIAN_CACHE"
+
+// DefaultCacheDir returns the cache directory: $LIBRARIAN_CACHE if set, or
+// "librarian" in the user cache directory.
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv(CacheEnv); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory (set %s): %w", CacheEnv, err)
+	}
+	return filepath.Join(dir, "librarian"), nil
+}
+
+// Cache is an on-disk cache of source tarballs.
+//
+// The tarball for a commit of a repository is stored at
+// Dir/<name>/<commit>.tar.gz and extracted into Dir/<name>/<commit>. The
+// checksum of the extracted tarball is kept in Dir/<name>/<commit>.sha256.
+type Cache struct {
+	Dir string
+
+	// Client is the HTTP client used for downloads. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// Tarball identifies a source tarball.
+type Tarball struct {
+	// Name is the repository name, such as "googleapis".
+	Name string
+
+	// Commit is the full commit SHA the tarball was built from.
+	Commit string
+
+	// URL is the download URL of the tarball.
+	URL string
+
+	// SHA256 is the expected checksum of the tarball. If empty, the
+	// tarball is not verified.
+	SHA256 string
+}
+
+var commitRegex = regexp.MustCompile(`^[0-9a-f]{40}(?:[0-9a-f]{24})?$`)
+
+// Fetch returns the directory containing the extracted contents of t,
+// downloading the tarball if it is not already cached. It also returns the
+// SHA-256 checksum of the tarball.
+//
+// Fetch returns an error if t.Commit is not a full commit SHA, or if the
+// tarball does not match t.SHA256. A cached copy that does not match
+// t.SHA256 is discarded and downloaded again.
+func (c *Cache) Fetch(ctx context.Context, t *Tarball) (dir, sum string, err error) {
+	if t.Name == "" || strings.ContainsAny(t.Name, `/\`) {
+		return "", "", fmt.Errorf("invalid source name %q", t.Name)
+	}
+	if !commitRegex.MatchString(t.Commit) {
+		return "", "", fmt.Errorf("%s ref %q is not a full commit SHA", t.Name, t.Commit)
+	}
+	repoDir := filepath.Join(c.Dir, t.Name)
+	dir = filepath.Join(repoDir, t.Commit)
+	tarball := dir + ".tar.gz"
+	sumFile := dir + ".sha256"
+
+	if _, err := os.Stat(dir); err == nil {
+		sum, err := cachedSum(sumFile, tarball)
+		if err == nil && (t.SHA256 == "" || strings.EqualFold(sum, t.SHA256)) {
+			return dir, sum, nil
+		}
+		// The cached copy cannot be verified or does not match; fetch it
+		// again.
+		if err := os.RemoveAll(dir); err != nil {
+			return "", "", fmt.Errorf("failed to remove unverified source from cache: %w", err)
+		}
+		os.Remove(sumFile)
+		os.Remove(tarball)
+	}
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	sum, err = fileSHA256(tarball)
+	if os.IsNotExist(err) {
+		sum, err = c.download(ctx, t.URL, tarball)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if t.SHA256 != "" && !strings.EqualFold(sum, t.SHA256) {
+		os.Remove(tarball)
+		return "", "", fmt.Errorf("checksum mismatch for %s: got sha256 %s, want %s", t.URL, sum, t.SHA256)
+	}
+
+	// Extract into a temporary directory and rename it into place, so an
+	// interrupted extraction is never mistaken for a complete one.
+	tmp, err := os.MkdirTemp(repoDir, t.Commit+".tmp-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := extract(tarball, tmp); err != nil {
+		return "", "", fmt.Errorf("failed to extract %s: %w", tarball, err)
+	}
+	if err := os.WriteFile(sumFile, []byte(sum+"\n"), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to record checksum: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr == nil {
+			// Extracted concurrently by another process.
+			return dir, sum, nil
+		}
+		return "", "", fmt.Errorf("failed to move extracted source into cache: %w", err)
+	}
+	return dir, sum, nil
+}
+
+// cachedSum returns the checksum of an extracted tarball: the checksum
+// recorded in sumFile, or else the checksum of the tarball itself.
+func cachedSum(sumFile, tarball string) (string, error) {
+	if data, err := os.ReadFile(sumFile); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	return fileSHA256(tarball)
+}
+
+// download saves url to path and returns its SHA-256 checksum.
+func (c *Cache) download(ctx context.Context, url, path string) (string, error) {
+	if url == "" {
+		return "", fmt.Errorf("no download URL for %s", filepath.Base(path))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// extract extracts the gzipped tarball at path into dir. The top-level
+// directory of the archive, such as googleapis-<commit>/ in GitHub archives,
+// is removed.
+func extract(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, name, ok := strings.Cut(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if !ok || name == "" {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
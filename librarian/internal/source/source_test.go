@@ -0,0 +1,230 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// makeTarball returns a gzipped tarball containing files under a top-level
+// directory, like a GitHub archive.
+func makeTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "googleapis-abc123/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		hdr := &tar.Header{Name: "googleapis-abc123/" + name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// commit is the commit SHA of the test tarballs.
+const commit = "c288189b43c016dd3cf1ec73ce3cadee8b732f07"
+
+func sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func TestFetch(t *testing.T) {
+	tarball := makeTarball(t, map[string]string{
+		"google/cloud/secretmanager/v1/service.proto": "syntax = \"proto3\";",
+		"google/cloud/secretmanager/v1/BUILD.bazel":   "# build",
+	})
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(tarball)
+	}))
+	defer srv.Close()
+
+	cache := &Cache{Dir: t.TempDir()}
+	src := &Tarball{Name: "googleapis", Commit: commit, URL: srv.URL + "/abc123.tar.gz", SHA256: sum(tarball)}
+	dir, got, err := cache.Fetch(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(cache.Dir, "googleapis", commit); dir != want {
+		t.Errorf("Fetch() dir = %q, want %q", dir, want)
+	}
+	if got != src.SHA256 {
+		t.Errorf("Fetch() sum = %q, want %q", got, src.SHA256)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "google/cloud/secretmanager/v1/service.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(`syntax = "proto3";`, string(content)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// A second fetch is served from the cache, even when offline.
+	srv.Close()
+	if _, _, err := cache.Fetch(context.Background(), src); err != nil {
+		t.Fatalf("Fetch() from cache: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestFetchFromCachedTarball(t *testing.T) {
+	tarball := makeTarball(t, map[string]string{"README.md": "googleapis"})
+	cache := &Cache{Dir: t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(cache.Dir, "googleapis"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cache.Dir, "googleapis", commit+".tar.gz"), tarball, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No URL is needed when the tarball is already cached.
+	dir, got, err := cache.Fetch(context.Background(), &Tarball{Name: "googleapis", Commit: commit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != sum(tarball) {
+		t.Errorf("Fetch() sum = %q, want %q", got, sum(tarball))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFetchChecksumMismatch(t *testing.T) {
+	tarball := makeTarball(t, map[string]string{"README.md": "googleapis"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer srv.Close()
+
+	cache := &Cache{Dir: t.TempDir()}
+	_, _, err := cache.Fetch(context.Background(), &Tarball{
+		Name:   "googleapis",
+		Commit: commit,
+		URL:    srv.URL,
+		SHA256: strings.Repeat("0", 64),
+	})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Fetch() error = %v, want checksum mismatch", err)
+	}
+	for _, name := range []string{commit, commit + ".tar.gz"} {
+		if _, err := os.Stat(filepath.Join(cache.Dir, "googleapis", name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be cached after a checksum mismatch", name)
+		}
+	}
+}
+
+func TestFetchVerifiesCache(t *testing.T) {
+	old := makeTarball(t, map[string]string{"README.md": "old"})
+	current := makeTarball(t, map[string]string{"README.md": "current"})
+	serve := old
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(serve)
+	}))
+	defer srv.Close()
+
+	cache := &Cache{Dir: t.TempDir()}
+	if _, _, err := cache.Fetch(context.Background(), &Tarball{Name: "googleapis", Commit: commit, URL: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(cache.Dir, "googleapis", commit+".tar.gz"))
+
+	// The cached copy does not match the checksum, so it is downloaded
+	// again and verified.
+	serve = current
+	src := &Tarball{Name: "googleapis", Commit: commit, URL: srv.URL, SHA256: sum(current)}
+	dir, got, err := cache.Fetch(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != sum(current) {
+		t.Errorf("Fetch() sum = %q, want %q", got, sum(current))
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("current", string(content)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// A cached copy that does not match is never used.
+	src.SHA256 = sum(old)
+	if _, _, err := cache.Fetch(context.Background(), src); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Fetch() error = %v, want checksum mismatch", err)
+	}
+}
+
+func TestFetchError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	cache := &Cache{Dir: t.TempDir()}
+	for _, test := range []struct {
+		name string
+		src  *Tarball
+	}{
+		{"not found", &Tarball{Name: "googleapis", Commit: commit, URL: srv.URL}},
+		{"no url", &Tarball{Name: "googleapis", Commit: commit}},
+		{"invalid commit", &Tarball{Name: "googleapis", Commit: "../abc123", URL: srv.URL}},
+		{"branch", &Tarball{Name: "googleapis", Commit: "master", URL: srv.URL}},
+		{"abbreviated commit", &Tarball{Name: "googleapis", Commit: "c288189", URL: srv.URL}},
+		{"invalid name", &Tarball{Name: "../googleapis", Commit: commit, URL: srv.URL}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := cache.Fetch(context.Background(), test.src); err == nil {
+				t.Error("Fetch() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestExtractPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := "evil"
+	if err := tw.WriteHeader(&tar.Header{Name: "top/../../evil", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte(content))
+	tw.Close()
+	gz.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "evil.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := extract(path, filepath.Join(dir, "out")); err == nil {
+		t.Error("extract() succeeded, want error")
+	}
+}
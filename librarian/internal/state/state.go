@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/julieqiu/exp/librarian/internal/yamlfile"
 	"gopkg.in/yaml.v3"
 )
 
@@ -27,6 +28,7 @@ type GenerateState struct {
 	Googleapis GoogleapisState  `yaml:"googleapis"`
 	Discovery  DiscoveryState   `yaml:"discovery,omitempty"`
 	Metadata   *Metadata        `yaml:"metadata,omitempty"`
+	InputHash  string           `yaml:"input_hash,omitempty"` // Hash of the generation inputs, recorded by generate
 }
 
 // Metadata holds library-specific metadata.
@@ -45,8 +47,9 @@ type Metadata struct {
 
 // ContainerState tracks container metadata.
 type ContainerState struct {
-	Image string `yaml:"image"`
-	Tag   string `yaml:"tag"`
+	Image  string `yaml:"image"`
+	Tag    string `yaml:"tag"`
+	Digest string `yaml:"digest,omitempty"` // Manifest digest of the image that generated the code
 }
 
 // GoogleapisState tracks googleapis metadata.
@@ -63,9 +66,18 @@ type DiscoveryState struct {
 
 // ReleaseState tracks release metadata.
 type ReleaseState struct {
-	Version  string        `yaml:"version"`
-	Prepared *ReleaseInfo  `yaml:"prepared,omitempty"`
-	History  []ReleaseInfo `yaml:"history,omitempty"`
+	Version  string                  `yaml:"version"`
+	Prepared *ReleaseInfo            `yaml:"prepared,omitempty"`
+	History  []ReleaseInfo           `yaml:"history,omitempty"`
+	Branches map[string]*BranchState `yaml:"branches,omitempty"` // Maintenance branches, by branch name
+}
+
+// BranchState tracks the releases made from a maintenance branch, such as
+// release/v1.x. Version and History replace those of the ReleaseState for
+// releases prepared on the branch.
+type BranchState struct {
+	Version string        `yaml:"version"`
+	History []ReleaseInfo `yaml:"history,omitempty"`
 }
 
 // ReleaseInfo contains information about a specific release.
@@ -74,6 +86,7 @@ type ReleaseInfo struct {
 	Tag     string `yaml:"tag,omitempty"`
 	Commit  string `yaml:"commit,omitempty"`
 	Branch  string `yaml:"branch,omitempty"`
+	Notes   string `yaml:"notes,omitempty"` // Release notes, recorded by prepare
 }
 
 // API represents an API path with its generation configuration.
@@ -123,9 +136,15 @@ type DartLanguage struct {
 	Package string `yaml:"package,omitempty"` // Dart package name (e.g., "my_package")
 }
 
+// File returns the path of the .librarian.yaml file in the artifact's
+// directory.
+func File(artifactPath string) string {
+	return filepath.Join(artifactPath, stateFile)
+}
+
 // Load reads the .librarian.yaml file from the artifact's directory.
 func Load(artifactPath string) (*Artifact, error) {
-	path := filepath.Join(artifactPath, stateFile)
+	path := File(artifactPath)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -142,18 +161,14 @@ func Load(artifactPath string) (*Artifact, error) {
 	return &a, nil
 }
 
-// Save writes the artifact state to .librarian.yaml in the artifact's directory.
+// Save writes the artifact state to .librarian.yaml in the artifact's
+// directory. Only the fields that changed are rewritten, so comments and key
+// order in an existing file are kept.
 func (a *Artifact) Save(artifactPath string) error {
-	data, err := yaml.Marshal(a)
-	if err != nil {
-		return fmt.Errorf("failed to marshal artifact state: %w", err)
-	}
-
-	path := filepath.Join(artifactPath, stateFile)
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	path := File(artifactPath)
+	if err := yamlfile.Write(path, a); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
-
 	return nil
 }
 
@@ -0,0 +1,279 @@
+// Package versionfile updates the version recorded in an artifact's language
+// specific files, such as setup.py or gapic_version.py, when a release is
+// prepared.
+package versionfile
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/exp/librarian/internal/deps"
+)
+
+// Update sets the version in the version files of the artifact in dir, using
+// the conventions of language. A leading "v" is removed from version. It
+// returns the paths of the files that were changed. Languages without version
+// files are ignored.
+func Update(dir, language, version string) ([]string, error) {
+	version = strings.TrimPrefix(version, "v")
+	switch language {
+	case "python":
+		return updateTree(dir, version, pythonFiles)
+	case "rust":
+		return updateRust(dir, version)
+	case "dart":
+		return updateFiles(version, map[string]updateFunc{
+			filepath.Join(dir, "pubspec.yaml"): updatePubspec,
+		})
+	default:
+		return nil, nil
+	}
+}
+
+// updateTree updates every file under dir whose name is a key of updaters.
+func updateTree(dir, version string, updaters map[string]updateFunc) ([]string, error) {
+	var changed []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		update, ok := updaters[d.Name()]
+		if !ok {
+			return nil
+		}
+		ok, err = updateFile(path, version, update)
+		if err != nil {
+			return fmt.Errorf("failed to update version in %s: %w", path, err)
+		}
+		if ok {
+			changed = append(changed, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// skipDir reports whether the directory should not be searched for version
+// files: hidden directories, such as .git or .nox, and build output.
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == "build" || name == "dist" || name == "target"
+}
+
+// updateFunc returns content with its version set to version.
+type updateFunc func(content, version string) string
+
+// updateFiles updates the files that are keys of updaters. Files that do not
+// exist are ignored.
+func updateFiles(version string, updaters map[string]updateFunc) ([]string, error) {
+	var paths []string
+	for path := range updaters {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var changed []string
+	for _, path := range paths {
+		ok, err := updateFile(path, version, updaters[path])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update version in %s: %w", path, err)
+		}
+		if ok {
+			changed = append(changed, path)
+		}
+	}
+	return changed, nil
+}
+
+func updateFile(path, version string, update updateFunc) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	updated := update(string(data), version)
+	if updated == string(data) {
+		return false, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, []byte(updated), info.Mode().Perm())
+}
+
+// pythonFiles are the Python version files, keyed by file name.
+var pythonFiles = map[string]updateFunc{
+	"setup.py":         updateSetupPy,
+	"pyproject.toml":   updatePyprojectToml,
+	"gapic_version.py": updateGapicVersion,
+}
+
+var (
+	// setupVersionRegex matches a version assignment or keyword argument
+	// in setup.py, such as `version = "1.2.3"` or `version="1.2.3",`.
+	setupVersionRegex = regexp.MustCompile(`(?m)^(\s*version\s*=\s*)(["'])[^"'\n]*(["'])`)
+
+	// gapicVersionRegex matches the __version__ assignment in
+	// gapic_version.py, such as `__version__ = "1.2.3"  # {x-release-please-version}`.
+	gapicVersionRegex = regexp.MustCompile(`(?m)^(__version__\s*=\s*)(["'])[^"'\n]*(["'])`)
+
+	// tomlVersionRegex matches a version key in a TOML table.
+	tomlVersionRegex = regexp.MustCompile(`^(\s*version\s*=\s*)(["'])[^"'\n]*(["'])`)
+)
+
+func updateSetupPy(content, version string) string {
+	return setupVersionRegex.ReplaceAllString(content, "${1}${2}"+version+"${3}")
+}
+
+func updateGapicVersion(content, version string) string {
+	return gapicVersionRegex.ReplaceAllString(content, "${1}${2}"+version+"${3}")
+}
+
+// updatePyprojectToml sets the version in the [project] table. Versions in
+// other tables, such as tool settings, are left unchanged.
+func updatePyprojectToml(content, version string) string {
+	return updateTOMLTable(content, "project", version)
+}
+
+// updateTOMLTable sets the version key of the named TOML table.
+func updateTOMLTable(content, table, version string) string {
+	lines := strings.Split(content, "\n")
+	var current string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			current = strings.Trim(stripTOMLComment(trimmed), "[] ")
+			continue
+		}
+		if current == table {
+			lines[i] = tomlVersionRegex.ReplaceAllString(line, "${1}${2}"+version+"${3}")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func stripTOMLComment(line string) string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		return strings.TrimSpace(line[:i])
+	}
+	return line
+}
+
+// updateRust sets the version of the crate in dir/Cargo.toml. If the crate is
+// a workspace member, its entry in the [workspace.dependencies] table of the
+// workspace root is also updated, so that members depending on the crate
+// through the workspace require the new version.
+func updateRust(dir, version string) ([]string, error) {
+	manifest := filepath.Join(dir, "Cargo.toml")
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	changed, err := updateFiles(version, map[string]updateFunc{manifest: updateCargoPackage})
+	if err != nil {
+		return nil, err
+	}
+	root, err := findWorkspaceRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	crate := cargoPackageName(string(data))
+	if root == "" || crate == "" {
+		return changed, nil
+	}
+	ok, err := deps.UpdateWorkspaceRequirement(root, crate, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s requirement in %s: %w", crate, root, err)
+	}
+	if ok {
+		changed = append([]string{root}, changed...)
+	}
+	return changed, nil
+}
+
+// updateCargoPackage sets the version in the [package] table of a
+// Cargo.toml file. Versions inherited from the workspace are left unchanged.
+func updateCargoPackage(content, version string) string {
+	return updateTOMLTable(content, "package", version)
+}
+
+// cargoPackageName returns the name in the [package] table of a Cargo.toml
+// file.
+func cargoPackageName(content string) string {
+	var current string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(stripTOMLComment(line))
+		if strings.HasPrefix(trimmed, "[") {
+			current = strings.Trim(trimmed, "[] ")
+			continue
+		}
+		if current != "package" {
+			continue
+		}
+		if m := tomlNameRegex.FindStringSubmatch(trimmed); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+var tomlNameRegex = regexp.MustCompile(`^name\s*=\s*["']([^"']+)["']`)
+
+var workspaceRegex = regexp.MustCompile(`(?m)^\s*\[workspace\]`)
+
+// findWorkspaceRoot returns the path of the Cargo.toml of the workspace
+// containing dir, or an empty string if dir is not in a workspace. The
+// crate's own manifest is not considered, and the search stops at the root
+// of the git repository.
+func findWorkspaceRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(abs, ".git")); err == nil {
+		return "", nil
+	}
+	for d := filepath.Dir(abs); ; d = filepath.Dir(d) {
+		path := filepath.Join(d, "Cargo.toml")
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if err == nil && workspaceRegex.Match(data) {
+			rel, err := filepath.Rel(abs, path)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(dir, rel), nil
+		}
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil || filepath.Dir(d) == d {
+			return "", nil
+		}
+	}
+}
+
+// pubspecVersionRegex matches the top-level version of a pubspec.yaml file.
+var pubspecVersionRegex = regexp.MustCompile(`(?m)^(version:\s*)(["']?)[^"'\s#]+(["']?)`)
+
+func updatePubspec(content, version string) string {
+	return pubspecVersionRegex.ReplaceAllString(content, "${1}${2}"+version+"${3}")
+}
@@ -0,0 +1,180 @@
+package versionfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdatePython(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct{ before, after string }{
+		"setup.py": {
+			before: "import setuptools\n\nversion = \"0.6.0\"\n\nsetuptools.setup(\n    name=name,\n    version=version,\n    python_requires=\">=3.7\",\n)\n",
+			after:  "import setuptools\n\nversion = \"0.7.0rc1\"\n\nsetuptools.setup(\n    name=name,\n    version=version,\n    python_requires=\">=3.7\",\n)\n",
+		},
+		"pyproject.toml": {
+			before: "[project]\nname = \"google-ads-admanager\"\nversion = \"0.6.0\"\n\n[tool.black]\nversion = \"23.1\"\n",
+			after:  "[project]\nname = \"google-ads-admanager\"\nversion = \"0.7.0rc1\"\n\n[tool.black]\nversion = \"23.1\"\n",
+		},
+		"google/ads/admanager/gapic_version.py": {
+			before: "__version__ = \"0.6.0\"  # {x-release-please-version}\n",
+			after:  "__version__ = \"0.7.0rc1\"  # {x-release-please-version}\n",
+		},
+		"google/ads/admanager_v1/gapic_version.py": {
+			before: "__version__ = '0.6.0'\n",
+			after:  "__version__ = '0.7.0rc1'\n",
+		},
+		".nox/lib/gapic_version.py": {
+			before: "__version__ = \"0.1.0\"\n",
+			after:  "__version__ = \"0.1.0\"\n",
+		},
+	}
+	for name, f := range files {
+		writeFile(t, filepath.Join(dir, name), f.before)
+	}
+
+	changed, err := Update(dir, "python", "0.7.0rc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChanged := []string{
+		filepath.Join(dir, "google/ads/admanager/gapic_version.py"),
+		filepath.Join(dir, "google/ads/admanager_v1/gapic_version.py"),
+		filepath.Join(dir, "pyproject.toml"),
+		filepath.Join(dir, "setup.py"),
+	}
+	if diff := cmp.Diff(wantChanged, changed); diff != "" {
+		t.Errorf("Update() mismatch (-want +got):\n%s", diff)
+	}
+	for name, f := range files {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(f.after, string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestUpdateUnknownLanguage(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "setup.py"), "version = \"1.0.0\"\n")
+	changed, err := Update(dir, "go", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("Update() = %v, want no changes", changed)
+	}
+}
+
+func TestUpdateRust(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "Cargo.toml"), `[workspace]
+members = ["src/generated/cloud/secretmanager/v1", "src/auth"]
+
+[workspace.dependencies]
+google-cloud-auth                = { version = "0.22", path = "src/auth" }
+google-cloud-secretmanager-v1    = { version = "1.0.0", path = "src/generated/cloud/secretmanager/v1" }
+google-cloud-secretmanager-v1-x  = { version = "1.0.0", path = "src/x" }
+serde                            = "1.0.0"
+`)
+	dir := filepath.Join(root, "src/generated/cloud/secretmanager/v1")
+	writeFile(t, filepath.Join(dir, "Cargo.toml"), `[package]
+name    = "google-cloud-secretmanager-v1"
+version = "1.0.0"
+edition.workspace = true
+
+[dependencies]
+auth.workspace = true
+serde = { version = "1.0.0" }
+`)
+
+	changed, err := Update(dir, "rust", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, "Cargo.toml"), filepath.Join(dir, "Cargo.toml")}
+	if diff := cmp.Diff(want, changed); diff != "" {
+		t.Errorf("Update() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := os.ReadFile(filepath.Join(root, "Cargo.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRoot := `[workspace]
+members = ["src/generated/cloud/secretmanager/v1", "src/auth"]
+
+[workspace.dependencies]
+google-cloud-auth                = { version = "0.22", path = "src/auth" }
+google-cloud-secretmanager-v1    = { version = "1.1.0", path = "src/generated/cloud/secretmanager/v1" }
+google-cloud-secretmanager-v1-x  = { version = "1.0.0", path = "src/x" }
+serde                            = "1.0.0"
+`
+	if diff := cmp.Diff(wantRoot, string(got)); diff != "" {
+		t.Errorf("workspace Cargo.toml mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCrate := `[package]
+name    = "google-cloud-secretmanager-v1"
+version = "1.1.0"
+edition.workspace = true
+
+[dependencies]
+auth.workspace = true
+serde = { version = "1.0.0" }
+`
+	if diff := cmp.Diff(wantCrate, string(got)); diff != "" {
+		t.Errorf("crate Cargo.toml mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdateDart(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pubspec.yaml"), `name: google_cloud_secretmanager
+description: Google Cloud Secret Manager client.
+version: 0.1.0 # managed by librarian
+
+dependencies:
+  http: ^1.2.0
+`)
+	if _, err := Update(dir, "dart", "0.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "pubspec.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `name: google_cloud_secretmanager
+description: Google Cloud Secret Manager client.
+version: 0.2.0 # managed by librarian
+
+dependencies:
+  http: ^1.2.0
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
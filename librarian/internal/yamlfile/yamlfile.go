@@ -0,0 +1,242 @@
+// Package yamlfile writes YAML files that are also edited by hand. Rather
+// than replacing a file with the encoding of a value, it updates the existing
+// document in place, so that comments, key order, and quoting of the values
+// that did not change are kept and diffs stay small.
+package yamlfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Write writes the YAML encoding of v to the file at path. If the file
+// exists, only the values that differ from v are changed.
+func Write(path string, v any) error {
+	orig, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	data, err := Marshal(orig, v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Marshal returns the YAML encoding of v, updating the document orig rather
+// than encoding v from scratch. Keys that v no longer has are removed, new
+// keys are added after the key that precedes them in v, and values that are
+// unchanged are left as they are. Keys that orig does not have are not added
+// if their value in v is a zero value, such as "" or false, since they would
+// decode to the same value. List elements are matched by value, or for lists
+// of mappings by the first key, so that comments stay with their element
+// when elements are added or removed.
+//
+// The output is always indented by two spaces, regardless of the
+// indentation of orig.
+func Marshal(orig []byte, v any) ([]byte, error) {
+	var value yaml.Node
+	if err := value.Encode(v); err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(orig, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc.Content[0] = merge(doc.Content[0], &value)
+	} else {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&value}}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// merge returns the node new, reusing the parts of old that are unchanged.
+func merge(old, new *yaml.Node) *yaml.Node {
+	if old == nil {
+		return new
+	}
+	switch {
+	case old.Kind == yaml.MappingNode && new.Kind == yaml.MappingNode:
+		new.Content = mergeMapping(old.Content, new.Content)
+	case old.Kind == yaml.SequenceNode && new.Kind == yaml.SequenceNode:
+		new.Content = mergeSequence(old.Content, new.Content)
+	case old.Kind == yaml.ScalarNode && new.Kind == yaml.ScalarNode:
+		if old.Value == new.Value && old.ShortTag() == new.ShortTag() {
+			return old
+		}
+		if keepStyle(old, new) {
+			new.Style = old.Style
+		}
+	}
+	if new.Kind == old.Kind {
+		new.Style |= old.Style & yaml.FlowStyle
+	}
+	new.HeadComment, new.LineComment, new.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	return new
+}
+
+// mergeMapping merges the key-value pairs of two mapping nodes, keeping the
+// order of old.
+func mergeMapping(old, new []*yaml.Node) []*yaml.Node {
+	index := func(pairs []*yaml.Node, key string) int {
+		for i := 0; i+1 < len(pairs); i += 2 {
+			if pairs[i].Value == key {
+				return i
+			}
+		}
+		return -1
+	}
+	var out []*yaml.Node
+	for i := 0; i+1 < len(old); i += 2 {
+		if j := index(new, old[i].Value); j >= 0 {
+			out = append(out, old[i], merge(old[i+1], new[j+1]))
+		}
+	}
+	for j := 0; j+1 < len(new); j += 2 {
+		if index(out, new[j].Value) >= 0 {
+			continue
+		}
+		if prune(new[j+1]); isZero(new[j+1]) {
+			continue
+		}
+		// Insert after the nearest earlier key of new that is in out. Earlier
+		// keys with zero values were not added, so they cannot be used.
+		at := 0
+		for k := j - 2; k >= 0; k -= 2 {
+			if i := index(out, new[k].Value); i >= 0 {
+				at = i + 2
+				break
+			}
+		}
+		out = append(out[:at], append([]*yaml.Node{new[j], new[j+1]}, out[at:]...)...)
+	}
+	return out
+}
+
+// mergeSequence merges the elements of two sequence nodes, keeping the order
+// of new. Each new element is merged with the old element that has the same
+// value, or for mappings the same value of its first key. Remaining elements
+// are merged with the unmatched old element at the same index, so that an
+// element edited in place keeps its comments.
+func mergeSequence(old, new []*yaml.Node) []*yaml.Node {
+	used := make([]bool, len(old))
+	match := make([]int, len(new))
+	for i, n := range new {
+		match[i] = -1
+		for j, o := range old {
+			if !used[j] && sameElement(o, n) {
+				match[i] = j
+				used[j] = true
+				break
+			}
+		}
+	}
+	for i := range new {
+		if match[i] < 0 && i < len(old) && !used[i] {
+			match[i] = i
+			used[i] = true
+		}
+	}
+	for i, n := range new {
+		if match[i] < 0 {
+			prune(n)
+			continue
+		}
+		new[i] = merge(old[match[i]], n)
+	}
+	return new
+}
+
+// sameElement reports whether the sequence elements old and new are the same
+// element: scalars with the same value, or mappings whose first key in new
+// has the same scalar value in old.
+func sameElement(old, new *yaml.Node) bool {
+	switch {
+	case old.Kind == yaml.ScalarNode && new.Kind == yaml.ScalarNode:
+		return old.Value == new.Value && old.ShortTag() == new.ShortTag()
+	case old.Kind == yaml.MappingNode && new.Kind == yaml.MappingNode:
+		if len(new.Content) < 2 || new.Content[1].Kind != yaml.ScalarNode {
+			return false
+		}
+		key, value := new.Content[0].Value, new.Content[1]
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			if old.Content[i].Value == key {
+				v := old.Content[i+1]
+				return v.Kind == yaml.ScalarNode && v.Value == value.Value && v.ShortTag() == value.ShortTag()
+			}
+		}
+	}
+	return false
+}
+
+// isZero reports whether n encodes a zero value: null, an empty string,
+// false, zero, or an empty sequence. Empty mappings are not zero values,
+// since they may encode a map entry or a non-nil pointer to a struct.
+func isZero(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			return true
+		case "!!str":
+			return n.Value == ""
+		case "!!bool":
+			return n.Value == "false"
+		case "!!int", "!!float":
+			return n.Value == "0"
+		}
+	case yaml.SequenceNode:
+		return len(n.Content) == 0
+	}
+	return false
+}
+
+// prune removes the keys with zero values from the mappings in n, which is
+// being added to the document.
+func prune(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		var out []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if prune(n.Content[i+1]); isZero(n.Content[i+1]) {
+				continue
+			}
+			out = append(out, n.Content[i], n.Content[i+1])
+		}
+		n.Content = out
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			prune(c)
+		}
+	}
+}
+
+// keepStyle reports whether a changed string value should be written in the
+// style of the value it replaces, such as single quotes or a literal block.
+func keepStyle(old, new *yaml.Node) bool {
+	if old.ShortTag() != "!!str" || new.ShortTag() != "!!str" {
+		return false
+	}
+	switch old.Style {
+	case yaml.SingleQuotedStyle, yaml.DoubleQuotedStyle:
+		return true
+	case yaml.LiteralStyle, yaml.FoldedStyle:
+		return strings.Contains(new.Value, "\n")
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package yamlfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+type release struct {
+	Version  string   `yaml:"version"`
+	Tag      string   `yaml:"tag,omitempty"`
+	Branch   string   `yaml:"branch,omitempty"`
+	Notes    string   `yaml:"notes,omitempty"`
+	Exclude  []string `yaml:"exclude,omitempty"`
+	Previous *release `yaml:"previous,omitempty"`
+}
+
+func TestMarshal(t *testing.T) {
+	orig := `# Release state, updated by librarian prepare.
+exclude: [internal/, testdata/]
+version:    v1.2.0    # current
+tag: 'secretmanager-v1.2.0'
+notes: |-
+    ### Features
+
+    * add GetSecret API
+previous:
+    version: v1.1.0
+    tag: secretmanager-v1.1.0 # first GA release
+`
+	for _, test := range []struct {
+		name  string
+		value *release
+		want  string
+	}{
+		{
+			name: "unchanged",
+			value: &release{
+				Version:  "v1.2.0",
+				Tag:      "secretmanager-v1.2.0",
+				Notes:    "### Features\n\n* add GetSecret API",
+				Exclude:  []string{"internal/", "testdata/"},
+				Previous: &release{Version: "v1.1.0", Tag: "secretmanager-v1.1.0"},
+			},
+			want: `# Release state, updated by librarian prepare.
+exclude: [internal/, testdata/]
+version: v1.2.0 # current
+tag: 'secretmanager-v1.2.0'
+notes: |-
+  ### Features
+
+  * add GetSecret API
+previous:
+  version: v1.1.0
+  tag: secretmanager-v1.1.0 # first GA release
+`,
+		},
+		{
+			name: "changed",
+			value: &release{
+				Version:  "v1.3.0",
+				Tag:      "secretmanager-v1.3.0",
+				Branch:   "main",
+				Notes:    "### Bug Fixes\n\n* retry on UNAVAILABLE",
+				Exclude:  []string{"internal/"},
+				Previous: &release{Version: "v1.2.0"},
+			},
+			want: `# Release state, updated by librarian prepare.
+exclude: [internal/]
+version: v1.3.0 # current
+tag: 'secretmanager-v1.3.0'
+branch: main
+notes: |-
+  ### Bug Fixes
+
+  * retry on UNAVAILABLE
+previous:
+  version: v1.2.0
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Marshal([]byte(orig), test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type branches struct {
+	Patterns []branchPattern `yaml:"patterns"`
+	Exclude  []string        `yaml:"exclude,omitempty"`
+}
+
+type branchPattern struct {
+	Pattern    string `yaml:"pattern"`
+	Prerelease string `yaml:"prerelease"`
+}
+
+func TestMarshalSequence(t *testing.T) {
+	orig := `patterns:
+  # Stable releases.
+  - pattern: main
+  # Release candidates.
+  - pattern: next
+    prerelease: rc # tracked by the release team
+exclude:
+  - internal/ # generated
+  - testdata/ # fixtures
+`
+	for _, test := range []struct {
+		name  string
+		value *branches
+		want  string
+	}{
+		{
+			name: "element removed",
+			value: &branches{
+				Patterns: []branchPattern{{Pattern: "next", Prerelease: "rc"}},
+				Exclude:  []string{"testdata/"},
+			},
+			want: `patterns:
+  # Release candidates.
+  - pattern: next
+    prerelease: rc # tracked by the release team
+exclude:
+  - testdata/ # fixtures
+`,
+		},
+		{
+			name: "element inserted",
+			value: &branches{
+				Patterns: []branchPattern{{Pattern: "dev", Prerelease: "alpha"}, {Pattern: "main"}, {Pattern: "next", Prerelease: "rc"}},
+				Exclude:  []string{"internal/", "testdata/"},
+			},
+			want: `patterns:
+  - pattern: dev
+    prerelease: alpha
+  # Stable releases.
+  - pattern: main
+  # Release candidates.
+  - pattern: next
+    prerelease: rc # tracked by the release team
+exclude:
+  - internal/ # generated
+  - testdata/ # fixtures
+`,
+		},
+		{
+			name: "element edited in place",
+			value: &branches{
+				Patterns: []branchPattern{{Pattern: "main"}, {Pattern: "preview", Prerelease: "rc"}},
+				Exclude:  []string{"internal/", "testdata/"},
+			},
+			want: `patterns:
+  # Stable releases.
+  - pattern: main
+  # Release candidates.
+  - pattern: preview
+    prerelease: rc # tracked by the release team
+exclude:
+  - internal/ # generated
+  - testdata/ # fixtures
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Marshal([]byte(orig), test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarshalZeroValues(t *testing.T) {
+	orig := "version: v1.0.0\n"
+	got, err := Marshal([]byte(orig), &release{Version: "v1.0.0", Branch: "", Previous: &release{Version: "", Tag: "v0.9.0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The zero values of the added previous release are not written, but
+	// previous itself is, since it is not nil.
+	want := "version: v1.0.0\nprevious:\n  tag: v0.9.0\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalAfterZeroValue(t *testing.T) {
+	type pattern struct {
+		Pattern     string `yaml:"pattern"`
+		Prerelease  string `yaml:"prerelease"`
+		Maintenance bool   `yaml:"maintenance"`
+		Notes       bool   `yaml:"notes"`
+		Label       string `yaml:"label"`
+	}
+	orig := "pattern: release/* # keep\n"
+	got, err := Marshal([]byte(orig), &pattern{Pattern: "release/*", Maintenance: true, Label: "lts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// New keys after a zero-valued key that was not written go after the
+	// nearest earlier key that was.
+	want := "pattern: release/* # keep\nmaintenance: true\nlabel: lts\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release.yaml")
+	value := &release{Version: "v1.0.0", Exclude: []string{"internal/"}}
+	if err := Write(path, value); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "version: v1.0.0\nexclude:\n  - internal/\n"
+	if diff := cmp.Diff(want, string(data)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	var got release
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(value, &got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}